@@ -0,0 +1,85 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/api_keys/inputs"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"errors"
+	"net/http"
+)
+
+// Handler for "GET /v1/users/me/api-keys" endpoint
+func (app *application) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := app.models.APIKeys.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"api_keys": keys}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "POST /v1/users/me/api-keys" endpoint
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.CreateAPIKeyInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	userPermissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateCreateAPIKeyInput(v, &input, userPermissions); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The response is the only place where the plaintext key appears, only its hash is stored
+	key, err := app.models.APIKeys.New(user.ID, input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"api_key": key}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "DELETE /v1/users/me/api-keys/:id" endpoint
+func (app *application) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.models.APIKeys.Delete(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "API key successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/api_keys"
+	"cinepulse.nlt.net/internal/data/users"
+	"context"
+	"net/http"
+)
+
+type contextKey string
+
+const (
+	userContextKey   = contextKey("user")
+	apiKeyContextKey = contextKey("api_key")
+)
+
+// contextSetUser returns a copy of the request with the provided User added to its context
+func (app *application) contextSetUser(r *http.Request, user *users.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// contextGetUser retrieves the User from the request context. It should only be called
+// when we logically expect a User to be there, which is why we panic otherwise
+func (app *application) contextGetUser(r *http.Request) *users.User {
+	user, ok := r.Context().Value(userContextKey).(*users.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+	return user
+}
+
+// contextSetAPIKey returns a copy of the request with the API key it was authenticated with added to its context
+func (app *application) contextSetAPIKey(r *http.Request, key *api_keys.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// contextGetAPIKey retrieves the API key the request was authenticated with. It returns nil when
+// the request was made with a session token or anonymously
+func (app *application) contextGetAPIKey(r *http.Request) *api_keys.APIKey {
+	key, _ := r.Context().Value(apiKeyContextKey).(*api_keys.APIKey)
+	return key
+}
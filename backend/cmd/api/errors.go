@@ -61,8 +61,52 @@ func (app *application) conflictResponse(w http.ResponseWriter, r *http.Request,
 	app.errorResponse(w, r, http.StatusConflict, err.Error())
 }
 
+// This helper is for when a user tries to review a movie they already reviewed. We point the client
+// at the existing review so that it can offer to edit it instead
+func (app *application) duplicateMovieReviewResponse(w http.ResponseWriter, r *http.Request, existingReviewID int64) {
+	message := envelope{
+		"message":         "you have already reviewed this movie",
+		"existing_review": fmt.Sprintf("/v1/reviews/%d", existingReviewID),
+		"review_id":       existingReviewID,
+	}
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
 // This helper is for when we want to send a 429 Too Many Requests response
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
+
+// This helper is for when the client sends a wrong email and password combination
+func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid authentication credentials"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+// This helper is for when the client sends a missing, malformed, expired or unknown authentication token
+func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	// Let the client know that we expect a bearer token
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing authentication token"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+// This helper is for when an anonymous user tries to access a resource requiring authentication
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you must be authenticated to access this resource"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+// This helper is for when an authenticated user hasn't activated their account yet
+func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account must be activated to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// This helper is for when an authenticated user tries to act on a resource they do not own
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
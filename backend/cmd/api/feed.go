@@ -0,0 +1,40 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/movie_reviews/inputs"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"net/http"
+)
+
+// Handler for "GET /v1/feed" endpoint
+func (app *application) showFeedHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.FeedQueryInput
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	after := app.readCursor(qs, "cursor", shared.FeedCursor, v)
+	input.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	if inputs.ValidateFeedQueryInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reviews, metadata, next, err := app.models.MovieReviews.GetFeed(app.contextGetUser(r).ID, after, &input)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if next != nil {
+		metadata.NextCursor = next.Encode([]byte(app.config.cursor.secret))
+	}
+	metadata.AddLinks(r.URL)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie_reviews": reviews, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/follows"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"errors"
+	"net/http"
+)
+
+// Handler for "POST /v1/profiles/:id/follow" endpoint
+func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	status, err := app.models.Follows.Follow(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, follows.ErrSelfFollow):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// A follow request still has to be accepted by the protected user
+	statusCode := http.StatusOK
+	if status == follows.Requested {
+		statusCode = http.StatusAccepted
+	}
+
+	err = app.writeJSON(w, statusCode, envelope{"follow": envelope{"user_id": id, "status": status}}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "DELETE /v1/profiles/:id/follow" endpoint
+func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Follows.Unfollow(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully unfollowed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "GET /v1/profiles/:id/followers" endpoint
+func (app *application) listFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.models.Follows.GetFollowers, "followers")
+}
+
+// Handler for "GET /v1/profiles/:id/following" endpoint
+func (app *application) listFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.models.Follows.GetFollowing, "following")
+}
+
+// listFollows sends one of the follow lists of the user identified by the ":id" URL parameter,
+// provided that the user making the request is allowed to see it
+func (app *application) listFollows(w http.ResponseWriter, r *http.Request,
+	getList func(int64, *shared.PageInput) ([]*follows.FollowUser, shared.Metadata, error), key string) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	input := app.readPageInput(r.URL.Query(), v)
+
+	if shared.ValidatePageInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	canView, err := app.models.Follows.CanViewFollows(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !canView {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	followUsers, metadata, err := getList(id, &input)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	metadata.AddLinks(r.URL)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{key: followUsers, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "GET /v1/users/me/follow-requests" endpoint
+func (app *application) listFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	input := app.readPageInput(r.URL.Query(), v)
+
+	if shared.ValidatePageInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	requesters, metadata, err := app.models.Follows.GetFollowRequests(app.contextGetUser(r).ID, &input)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	metadata.AddLinks(r.URL)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"follow_requests": requesters, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "PUT /v1/users/me/follow-requests/:id" endpoint, where ":id" is the requester's ID
+func (app *application) acceptFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Follows.AcceptFollowRequest(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "follow request successfully accepted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "DELETE /v1/users/me/follow-requests/:id" endpoint, where ":id" is the requester's ID
+func (app *application) rejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Follows.RejectFollowRequest(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "follow request successfully rejected"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
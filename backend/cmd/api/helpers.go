@@ -1,6 +1,8 @@
 package main
 
 import (
+	"cinepulse.nlt.net/internal/data/movies"
+	"cinepulse.nlt.net/internal/data/shared"
 	"cinepulse.nlt.net/internal/validator"
 	"encoding/json"
 	"errors"
@@ -11,6 +13,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type envelope map[string]any
@@ -25,6 +28,36 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// readReactionParam reads the ":type" URL parameter used by the movie review reactions endpoints
+func (app *application) readReactionParam(r *http.Request) string {
+	params := httprouter.ParamsFromContext(r.Context())
+	return params.ByName("type")
+}
+
+// readHandleParam reads the ":handle" URL parameter identifying a user by their profile handle
+func (app *application) readHandleParam(r *http.Request) string {
+	params := httprouter.ParamsFromContext(r.Context())
+	return params.ByName("handle")
+}
+
+// readImdbIDParam reads the ":imdb_id" URL parameter used by the movies endpoints and returns it
+// in its canonical form
+func (app *application) readImdbIDParam(r *http.Request) (string, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	imdbID := movies.CanonicalImdbID(params.ByName("imdb_id"))
+	if !validator.Matches(imdbID, movies.ImdbIDRX) {
+		return "", errors.New("invalid imdb_id parameter")
+	}
+	return imdbID, nil
+}
+
+// readPermissionParam reads the ":code" URL parameter used by the permissions administration endpoints
+func (app *application) readPermissionParam(r *http.Request) string {
+	params := httprouter.ParamsFromContext(r.Context())
+	return params.ByName("code")
+}
+
 func (app *application) writeJSON(w http.ResponseWriter, statusCode int, data envelope, headers http.Header) error {
 	// Format the JSON to make it easier to read on terminal apps
 	jsonBytes, err := json.MarshalIndent(data, "", "\t")
@@ -40,6 +73,11 @@ func (app *application) writeJSON(w http.ResponseWriter, statusCode int, data en
 		w.Header()[key] = value
 	}
 
+	// Paginated responses carry the links to their other pages in a Link header
+	if metadata, ok := data["metadata"].(shared.Metadata); ok && len(metadata.Links) > 0 {
+		w.Header().Set("Link", metadata.LinkHeader())
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	_, err = w.Write(jsonBytes)
@@ -123,6 +161,17 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// readString() returns a string value from the query string, or the provided default value if no
+// matching key could be found
+func (app *application) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
 // readInt() reads a string value from the query string and converts it to an integer before
 // returning. If no match is found, it returns the provided default value
 func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
@@ -140,6 +189,67 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// readBool() reads a boolean value from the query string. If no match is found, it returns the provided default value
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
+// readTime() reads an RFC 3339 timestamp or a plain "2006-01-02" date from the query string. If no
+// match is found, it returns nil
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return &t
+		}
+	}
+
+	v.AddError(key, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	return nil
+}
+
+// readPageInput reads the "page" and "page_size" query string parameters of the page-based listings
+func (app *application) readPageInput(qs url.Values, v *validator.Validator) shared.PageInput {
+	return shared.PageInput{
+		Page:     app.readInt(qs, "page", 1, v),
+		PageSize: app.readInt(qs, "page_size", 20, v),
+	}
+}
+
+// readCursor() decodes a signed pagination cursor of the given kind from the query string. It returns
+// nil when the value is empty, which stands for the first page
+func (app *application) readCursor(qs url.Values, key string, kind shared.CursorKind, v *validator.Validator) *shared.Cursor {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	cursor, err := shared.DecodeCursor(s, kind, []byte(app.config.cursor.secret))
+	if err != nil {
+		v.AddError(key, "must be a valid cursor")
+		return nil
+	}
+	return cursor
+}
+
 // backgroundTask() helper accepts an arbitrary function as parameter which should be run as a background task in
 // a separate Goroutine
 func (app *application) backgroundTask(fn func()) {
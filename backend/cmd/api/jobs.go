@@ -0,0 +1,45 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/mailer"
+	"cinepulse.nlt.net/internal/mailer/types"
+	"context"
+	"time"
+)
+
+// purgeDeletedAccounts deletes the accounts whose grace period is over, every purge interval, until
+// the context is cancelled
+func (app *application) purgeDeletedAccounts(ctx context.Context) {
+	ticker := time.NewTicker(app.config.deletion.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := app.models.Users.PurgeScheduledDeletions(ctx)
+		if err != nil {
+			app.logger.Error(err.Error())
+			continue
+		}
+
+		if len(deleted) > 0 {
+			app.logger.Info("purged deleted accounts", "count", len(deleted))
+		}
+
+		for _, user := range deleted {
+			var data = types.AccountDeletedTemplateData{
+				ProfileHandle: user.ProfileHandle,
+				CurrentYear:   time.Now().Year(),
+			}
+
+			err = app.mailer.Send(user.Email, mailer.AccountDeletedTemplate, data)
+			if err != nil {
+				app.logger.Error(err.Error())
+			}
+		}
+	}
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"cinepulse.nlt.net/internal/data"
+	"cinepulse.nlt.net/internal/data/movies"
 	"cinepulse.nlt.net/internal/mailer"
+	"cinepulse.nlt.net/internal/metadata"
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"flag"
+	"fmt"
 	_ "github.com/lib/pq"
 	"log/slog"
 	"os"
@@ -36,14 +41,27 @@ type config struct {
 		password string
 		sender   string
 	}
+	cursor struct {
+		secret string
+	}
+	deletion struct {
+		gracePeriod   time.Duration
+		purgeInterval time.Duration
+	}
+	metadata struct {
+		provider   string
+		omdbAPIKey string
+		file       string
+	}
 }
 
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config   config
+	logger   *slog.Logger
+	models   data.Models
+	mailer   mailer.Mailer
+	metadata movies.MetadataProvider // Nil when no provider is configured
+	wg       sync.WaitGroup
 }
 
 func main() {
@@ -71,11 +89,35 @@ func main() {
 	flag.StringVar(&cfg.smtp.password, "smtp-password", "33a52a668e70c9", "SMTP server password")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "Cinepulse <no-reply@cinepulse.nlt.net>", "SMTP server username")
 
+	// Pagination cursors settings
+	flag.StringVar(&cfg.cursor.secret, "cursor-secret", os.Getenv("CINEPULSE_CURSOR_SECRET"), "Secret used to sign pagination cursors")
+
+	// Accounts deletion settings
+	flag.DurationVar(&cfg.deletion.gracePeriod, "deletion-grace-period", 30*24*time.Hour, "Delay before a deleted account is purged")
+	flag.DurationVar(&cfg.deletion.purgeInterval, "deletion-purge-interval", time.Hour, "Interval between the purges of deleted accounts")
+
+	// Movies metadata provider settings
+	flag.StringVar(&cfg.metadata.provider, "metadata-provider", "none", "Movies metadata provider (omdb|file|none)")
+	flag.StringVar(&cfg.metadata.omdbAPIKey, "omdb-api-key", os.Getenv("CINEPULSE_OMDB_API_KEY"), "OMDb API key")
+	flag.StringVar(&cfg.metadata.file, "metadata-file", "", "JSON file of movies used by the file provider")
+
 	flag.Parse()
 
 	// Initialize a new structured logger which writes log entries to the standard out stream
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+	// Without a configured secret, we use a random one. Cursors handed out will then stop working after a restart
+	if cfg.cursor.secret == "" {
+		logger.Warn("no cursor secret configured, using a random one")
+		cfg.cursor.secret = rand.Text()
+	}
+
+	metadataProvider, err := openMetadataProvider(cfg, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.Error(err.Error())
@@ -93,10 +135,11 @@ func main() {
 	logger.Info("Database connection pool established")
 
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:   cfg,
+		logger:   logger,
+		models:   data.NewModels(db),
+		mailer:   mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		metadata: metadataProvider,
 	}
 
 	err = app.serve()
@@ -130,3 +173,29 @@ func openDB(cfg config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// openMetadataProvider returns the movies metadata provider selected in the config, if any
+func openMetadataProvider(cfg config, logger *slog.Logger) (movies.MetadataProvider, error) {
+	switch cfg.metadata.provider {
+	case "omdb":
+		if cfg.metadata.omdbAPIKey == "" {
+			return nil, errors.New("the omdb metadata provider requires -omdb-api-key")
+		}
+		return metadata.NewOMDb(cfg.metadata.omdbAPIKey), nil
+	case "file":
+		if cfg.metadata.file == "" {
+			return nil, errors.New("the file metadata provider requires -metadata-file")
+		}
+		provider, err := metadata.NewFile(cfg.metadata.file)
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	case "none":
+		// Reviews are still accepted, but their IMDb IDs aren't checked and the movies aren't catalogued
+		logger.Warn("no movies metadata provider configured, the IMDb IDs of the reviews won't be checked")
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown metadata provider %q", cfg.metadata.provider)
+	}
+}
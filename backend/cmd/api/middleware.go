@@ -1,10 +1,18 @@
 package main
 
 import (
+	"cinepulse.nlt.net/internal/data/api_keys"
+	"cinepulse.nlt.net/internal/data/permissions"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/data/tokens"
+	"cinepulse.nlt.net/internal/data/users"
+	"cinepulse.nlt.net/internal/validator"
+	"errors"
 	"fmt"
 	"golang.org/x/time/rate"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
@@ -77,3 +85,176 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// authenticate resolves the "Authorization: Bearer <token>" header to a user and stores it in
+// the request context. Requests without the header are associated with the AnonymousUser.
+// The bearer can either be a session token or an API key, in which case the key is stored as well
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response varies depending on the Authorization header, so caches must take it into account
+		w.Header().Add("Vary", "Authorization")
+
+		authorizationHeader := r.Header.Get("Authorization")
+		if authorizationHeader == "" {
+			r = app.contextSetUser(r, users.AnonymousUser)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		headerParts := strings.Split(authorizationHeader, " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		token := headerParts[1]
+
+		if api_keys.IsAPIKey(token) {
+			app.authenticateAPIKey(w, r, token, next)
+			return
+		}
+
+		v := validator.New()
+		if tokens.ValidateTokenPlaintext(v, token); !v.Valid() {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		user, err := app.models.Users.GetForToken(tokens.ScopeAuthentication, token)
+		if err != nil {
+			switch {
+			case errors.Is(err, shared.ErrRecordNotFound):
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		r = app.contextSetUser(r, user)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticateAPIKey is the part of authenticate resolving an API key to its owner
+func (app *application) authenticateAPIKey(w http.ResponseWriter, r *http.Request, plaintext string, next http.Handler) {
+	key, err := app.models.APIKeys.Authenticate(plaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.GetByEmailOrId(users.ID, key.UserID)
+	if err != nil {
+		switch {
+		// The owner was purged since the key was checked, the key went away with them
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Like the session tokens, API keys stop working while the account is waiting to be deleted
+	if user.DeletionScheduledAt != nil {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	r = app.contextSetUser(r, user)
+	r = app.contextSetAPIKey(r, key)
+	next.ServeHTTP(w, r)
+}
+
+// requireAuthenticatedUser makes sure that the request was not made by an anonymous user
+func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if user.IsAnonymous() {
+			app.authenticationRequiredResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// requireActivatedUser makes sure that the request was made by an authenticated user with an activated account
+func (app *application) requireActivatedUser(next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if !user.IsActivated {
+			app.inactiveAccountResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return app.requireAuthenticatedUser(fn)
+}
+
+// requirePermission makes sure that the request was made by an activated user who was granted the given permission
+func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		userPermissions, err := app.permissionsFor(r)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !userPermissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return app.requireActivatedUser(fn)
+}
+
+// requireSessionUser makes sure that the request was made by an authenticated user with a session token.
+// Managing the account and its credentials can't be done with an API key, whatever its scopes
+func (app *application) requireSessionUser(next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetAPIKey(r) != nil {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return app.requireAuthenticatedUser(fn)
+}
+
+// permissionsFor returns the permissions the request is allowed to use. Requests authenticated with
+// an API key are restricted to the scopes of the key, in addition to the permissions of its owner
+func (app *application) permissionsFor(r *http.Request) (permissions.Permissions, error) {
+	userPermissions, err := app.models.Permissions.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := app.contextGetAPIKey(r)
+	if key == nil {
+		return userPermissions, nil
+	}
+
+	scoped := permissions.Permissions{}
+	for _, code := range userPermissions {
+		if key.Scopes.Include(code) {
+			scoped = append(scoped, code)
+		}
+	}
+	return scoped, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/movie_reviews"
+	"cinepulse.nlt.net/internal/data/movie_reviews/inputs"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"errors"
+	"net/http"
+)
+
+// Handler for "PUT /v1/reviews/:id/reactions/:type" endpoint
+func (app *application) addMovieReviewReactionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	reaction := app.readReactionParam(r)
+
+	v := validator.New()
+	if movie_reviews.ValidateReaction(v, reaction); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.MovieReviews.AddReaction(id, user.ID, reaction)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeMovieReviewAfterReaction(w, r, id)
+}
+
+// Handler for "DELETE /v1/reviews/:id/reactions/:type" endpoint
+func (app *application) removeMovieReviewReactionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	reaction := app.readReactionParam(r)
+
+	v := validator.New()
+	if movie_reviews.ValidateReaction(v, reaction); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.MovieReviews.RemoveReaction(id, user.ID, reaction)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeMovieReviewAfterReaction(w, r, id)
+}
+
+// writeMovieReviewAfterReaction sends back the review with its up-to-date reactions
+func (app *application) writeMovieReviewAfterReaction(w http.ResponseWriter, r *http.Request, id int64) {
+	movieReview, err := app.models.MovieReviews.Get(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movieReview": movieReview}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "GET /v1/reviews/:id/reactions" endpoint
+func (app *application) listMovieReviewReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input inputs.ListMovieReviewReactionsQueryInput
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Reaction = app.readString(qs, "type", "")
+	input.PageInput = app.readPageInput(qs, v)
+
+	if input.Reaction != "" {
+		movie_reviews.ValidateReaction(v, input.Reaction)
+	}
+	if inputs.ValidateListMovieReviewReactionsQueryInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reactors, metadata, err := app.models.MovieReviews.GetReactors(id, app.contextGetUser(r).ID, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	metadata.AddLinks(r.URL)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"reactions": reactors, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
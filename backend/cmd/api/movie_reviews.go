@@ -3,11 +3,14 @@ package main
 import (
 	"cinepulse.nlt.net/internal/data/movie_reviews"
 	"cinepulse.nlt.net/internal/data/movie_reviews/inputs"
+	"cinepulse.nlt.net/internal/data/movies"
+	"cinepulse.nlt.net/internal/data/permissions"
 	"cinepulse.nlt.net/internal/data/shared"
 	"cinepulse.nlt.net/internal/validator"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // Handler for "POST /v1/reviews" endpoint
@@ -18,7 +21,7 @@ func (app *application) createMovieReviewHandler(w http.ResponseWriter, r *http.
 	// We look at each property.
 	// In the following, the overhead mentioned are the extra bytes needed to encode
 	// the JSON property name like "imdb_id" or "rating"
-	// An imdb_id can take up to 9 bytes (
+	// An imdb_id can take up to 10 bytes, or around a hundred bytes when given as the URL of the IMDb title
 	// a rating is an integer value in [1:5] which only needs a byte
 	// StatementComment has at most 280 Characters which are UTF-8 characters for which a rune(32 bits) are needed
 	// which means we need 4 * 280 bytes ~= 1120 bytes
@@ -32,17 +35,39 @@ func (app *application) createMovieReviewHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// IMDb IDs are stored in their canonical form, so that all the reviews of a movie share the same one
+	input.ImdbID = movies.CanonicalImdbID(input.ImdbID)
+
 	v := validator.New()
 	if inputs.ValidateCreateMovieReviewInput(v, &input); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	result, err := app.models.MovieReviews.Insert(&input)
+	err = app.catalogueMovie(input.ImdbID)
+	if err != nil {
+		switch {
+		case errors.Is(err, movies.ErrMovieNotFound):
+			v.AddError("imdb_id", "must be the IMDb ID of an existing movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	result, err := app.models.MovieReviews.Insert(&input, user.ID)
 	if err != nil {
 		switch {
-		case errors.Is(err, movie_reviews.ErrDuplicateImdbID):
-			app.conflictResponse(w, r, errors.New("a review for the same imdb ID already exists"))
+		case errors.Is(err, movie_reviews.ErrDuplicateReview):
+			existingID, err := app.models.MovieReviews.GetIDForAuthorAndImdbID(user.ID, input.ImdbID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			app.duplicateMovieReviewResponse(w, r, existingID)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -61,25 +86,71 @@ func (app *application) createMovieReviewHandler(w http.ResponseWriter, r *http.
 
 // Handler for "GET /v1/reviews" endpoint
 func (app *application) listMovieReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	app.listMovieReviews(w, r, movies.CanonicalImdbID(app.readString(r.URL.Query(), "imdb_id", "")))
+}
+
+// listMovieReviews writes the reviews matching the query string filters. They are restricted to the
+// movie with the given IMDb ID unless it is empty
+func (app *application) listMovieReviews(w http.ResponseWriter, r *http.Request, imdbID string) {
 	var input inputs.ListMovieReviewsQueryInput
 
 	v := validator.New()
 	qs := r.URL.Query()
 
-	input.Page = app.readInt(qs, "page", 1, v)
-	input.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.ImdbID = imdbID
+	input.UserID = int64(app.readInt(qs, "user_id", 0, v))
+	input.Handle = app.readString(qs, "handle", "")
+	input.MinRating = app.readInt(qs, "min_rating", 0, v)
+	input.MaxRating = app.readInt(qs, "max_rating", 0, v)
+	input.CreatedAfter = app.readTime(qs, "created_after", v)
+	input.CreatedBefore = app.readTime(qs, "created_before", v)
+
+	input.Query = strings.TrimSpace(app.readString(qs, "q", ""))
+	input.Highlight = app.readBool(qs, "highlight", false, v)
+
+	// The presence of the "cursor" parameter switches to keyset pagination, an empty
+	// cursor meaning the first page. Otherwise, we keep the page-based pagination
+	input.CursorMode = qs.Has("cursor")
+	var after *shared.Cursor
+	if input.CursorMode {
+		after = app.readCursor(qs, "cursor", shared.ReviewsCursor, v)
+		v.AddErrorIfNot(!qs.Has("page"), "page", "cannot be used along with cursor")
+	}
+
+	// Search results are sorted by relevance unless specified otherwise
+	defaultSort := "-updated_at"
+	if input.Query != "" && !input.CursorMode {
+		defaultSort = "-relevance"
+	}
+	input.Sort = app.readString(qs, "sort", defaultSort)
+	input.PageInput = app.readPageInput(qs, v)
 
 	if inputs.ValidateListMovieReviewsQueryInput(v, &input); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	reviews, metadata, err := app.models.MovieReviews.GetAll(&input)
+	var (
+		reviews  []*movie_reviews.MovieReview
+		metadata shared.Metadata
+		err      error
+	)
+	if input.CursorMode {
+		var next *shared.Cursor
+		reviews, metadata, next, err = app.models.MovieReviews.GetAllByCursor(&input, after, app.contextGetUser(r).ID)
+		if next != nil {
+			metadata.NextCursor = next.Encode([]byte(app.config.cursor.secret))
+		}
+	} else {
+		reviews, metadata, err = app.models.MovieReviews.GetAll(&input, app.contextGetUser(r).ID)
+	}
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	metadata.AddLinks(r.URL)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"movie_reviews": reviews, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -95,7 +166,7 @@ func (app *application) showMovieReviewHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	movieReview, err := app.models.MovieReviews.Get(id)
+	movieReview, err := app.models.MovieReviews.Get(id, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, shared.ErrRecordNotFound):
@@ -120,6 +191,34 @@ func (app *application) deleteMovieReviewHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	_, authorID, err := app.models.MovieReviews.GetVersionAndAuthorFor(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Authors delete their reviews with the reviews:write permission, and moderators can delete the
+	// reviews of others. The permissions are checked here since API keys may be scoped to either one
+	userPermissions, err := app.permissionsFor(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	required := permissions.ReviewsModerate
+	if authorID == app.contextGetUser(r).ID {
+		required = permissions.ReviewsWrite
+	}
+	if !userPermissions.Include(required) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
 	err = app.models.MovieReviews.Delete(id)
 	if err != nil {
 		switch {
@@ -144,8 +243,8 @@ func (app *application) updateMovieReviewHandler(w http.ResponseWriter, r *http.
 		app.badRequestResponse(w, r, err)
 		return
 	}
-	// Fetch the version of the movieReview with given ID
-	movieReviewVersion, err := app.models.MovieReviews.GetVersionFor(id)
+	// Fetch the version and the author of the movieReview with given ID
+	movieReviewVersion, authorID, err := app.models.MovieReviews.GetVersionAndAuthorFor(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, shared.ErrRecordNotFound):
@@ -155,6 +254,12 @@ func (app *application) updateMovieReviewHandler(w http.ResponseWriter, r *http.
 		}
 		return
 	}
+
+	// Only the author of a review can edit it
+	if authorID != app.contextGetUser(r).ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
 	var input inputs.UpdateMovieReviewInput
 	err = app.readJSON(w, r, &input, 2048)
 	if err != nil {
@@ -178,13 +283,48 @@ func (app *application) updateMovieReviewHandler(w http.ResponseWriter, r *http.
 		switch {
 		case errors.Is(err, shared.ErrEditConflict):
 			app.conflictResponse(w, r, errors.New("unable to update the record due to an edit conflict, please try again"))
-			return
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
+		return
 	}
 	err = app.writeJSON(w, http.StatusOK, envelope{"movieReview": result}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// Handler for "GET /v1/reviews/:id/revisions" endpoint
+func (app *application) listMovieReviewRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	input := app.readPageInput(r.URL.Query(), v)
+
+	if shared.ValidatePageInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	revisions, metadata, err := app.models.MovieReviews.GetRevisions(id, app.contextGetUser(r).ID, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	metadata.AddLinks(r.URL)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/movie_reviews"
+	"cinepulse.nlt.net/internal/data/movies"
+	"cinepulse.nlt.net/internal/data/shared"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// catalogueMovie makes sure that the movie is in the catalogue, fetching it from the metadata provider
+// the first time it is reviewed. It returns movies.ErrMovieNotFound if the provider doesn't know the movie.
+// The provider being unavailable doesn't prevent reviewing: the movie is catalogued on a later review
+func (app *application) catalogueMovie(imdbID string) error {
+	if app.metadata == nil {
+		return nil
+	}
+
+	_, err := app.models.Movies.Get(imdbID)
+	switch {
+	case err == nil:
+		return nil
+	case !errors.Is(err, shared.ErrRecordNotFound):
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	movie, err := app.metadata.FetchMovie(ctx, imdbID)
+	if err != nil {
+		switch {
+		case errors.Is(err, movies.ErrMovieNotFound):
+			return err
+		default:
+			app.logger.Error("fetching movie metadata", "imdb_id", imdbID, "error", err.Error())
+			return nil
+		}
+	}
+
+	// Providers may answer with a differently formatted ID, the reviews are the reference
+	movie.ImdbID = imdbID
+	return app.models.Movies.Upsert(movie)
+}
+
+// Handler for "GET /v1/movies/:imdb_id" endpoint
+func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request) {
+	imdbID, err := app.readImdbIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// A movie may have been reviewed without being catalogued, when no metadata provider is available
+	movie, err := app.models.Movies.Get(imdbID)
+	if err != nil && !errors.Is(err, shared.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	stats, err := app.models.MovieReviews.GetStats(imdbID)
+	if err != nil {
+		switch {
+		case !errors.Is(err, shared.ErrRecordNotFound):
+			app.serverErrorResponse(w, r, err)
+			return
+		case movie == nil:
+			app.notFoundResponse(w, r)
+			return
+		default:
+			stats = &movie_reviews.MovieReviewStats{RatingHistogram: map[string]int64{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0}}
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"imdb_id": imdbID, "movie": movie, "stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "GET /v1/movies/:imdb_id/reviews" endpoint
+func (app *application) listMovieReviewsOfMovieHandler(w http.ResponseWriter, r *http.Request) {
+	imdbID, err := app.readImdbIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.listMovieReviews(w, r, imdbID)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/permissions"
+	"cinepulse.nlt.net/internal/data/permissions/inputs"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"errors"
+	"net/http"
+)
+
+// Handler for "GET /v1/profiles/:id/permissions" endpoint
+func (app *application) listUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userPermissions, err := app.models.Permissions.GetAllForUser(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user_id": id, "permissions": userPermissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "PUT /v1/profiles/:id/permissions" endpoint
+func (app *application) grantUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input inputs.GrantPermissionsInput
+
+	err = app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateGrantPermissionsInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Permissions.AddForUser(id, input.Permissions...)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	userPermissions, err := app.models.Permissions.GetAllForUser(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user_id": id, "permissions": userPermissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "DELETE /v1/profiles/:id/permissions/:code" endpoint
+func (app *application) revokeUserPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	code := app.readPermissionParam(r)
+
+	v := validator.New()
+	if permissions.ValidatePermission(v, code); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Permissions.RemoveForUser(id, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "permission successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
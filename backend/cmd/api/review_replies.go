@@ -0,0 +1,226 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/movie_reviews"
+	"cinepulse.nlt.net/internal/data/movie_reviews/inputs"
+	"cinepulse.nlt.net/internal/data/permissions"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Handler for "POST /v1/reviews/:id/replies" endpoint
+func (app *application) createReviewReplyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input inputs.CreateReviewReplyInput
+	err = app.readJSON(w, r, &input, 2048)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateCreateReviewReplyInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reply, err := app.models.MovieReviews.InsertReply(id, app.contextGetUser(r).ID, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, movie_reviews.ErrInvalidParentReply):
+			v.AddError("parent_id", "must be the ID of a top-level reply of the same review")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/replies/%d", reply.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"reply": reply}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "GET /v1/reviews/:id/replies" endpoint
+func (app *application) listReviewRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	input := app.readPageInput(r.URL.Query(), v)
+
+	if shared.ValidatePageInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	replies, metadata, err := app.models.MovieReviews.GetReplies(id, app.contextGetUser(r).ID, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	metadata.AddLinks(r.URL)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"replies": replies, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "PATCH /v1/replies/:id" endpoint
+func (app *application) updateReviewReplyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, authorID, reviewID, err := app.models.MovieReviews.GetReplyVersionAndAuthorFor(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Only the author of a reply can edit it, as long as they can still see the review
+	user := app.contextGetUser(r)
+	if authorID != user.ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.MovieReviews.CheckVisibility(reviewID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input inputs.UpdateReviewReplyInput
+	err = app.readJSON(w, r, &input, 2048)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateUpdateReviewReplyInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reply, err := app.models.MovieReviews.UpdateReply(&input, id, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrEditConflict):
+			app.conflictResponse(w, r, errors.New("unable to update the record due to an edit conflict, please try again"))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"reply": reply}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "DELETE /v1/replies/:id" endpoint
+func (app *application) deleteReviewReplyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	_, authorID, reviewID, err := app.models.MovieReviews.GetReplyVersionAndAuthorFor(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Like reviews, authors delete their replies with the reviews:write permission, and moderators
+	// can delete the replies of others
+	userPermissions, err := app.permissionsFor(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	required := permissions.ReviewsModerate
+	if authorID == user.ID {
+		required = permissions.ReviewsWrite
+	}
+	if !userPermissions.Include(required) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	// Authors who can't see the review anymore can't reach their reply either, moderators can
+	if authorID == user.ID {
+		err = app.models.MovieReviews.CheckVisibility(reviewID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, shared.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	err = app.models.MovieReviews.DeleteReply(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "reply successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
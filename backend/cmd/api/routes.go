@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cinepulse.nlt.net/internal/data/permissions"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
 )
@@ -19,13 +20,76 @@ func (app *application) routes() http.Handler {
 
 	// movie Reviews
 	router.HandlerFunc(http.MethodGet, "/v1/reviews", app.listMovieReviewsHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/reviews", app.createMovieReviewHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/reviews", app.requirePermission(permissions.ReviewsWrite, app.createMovieReviewHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/reviews/:id", app.showMovieReviewHandler)
-	router.HandlerFunc(http.MethodPatch, "/v1/reviews/:id", app.updateMovieReviewHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/reviews/:id", app.deleteMovieReviewHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/reviews/:id", app.requirePermission(permissions.ReviewsWrite, app.updateMovieReviewHandler))
+	// Moderators without the reviews:write permission can still delete the reviews of others, the handler checks the permissions
+	router.HandlerFunc(http.MethodDelete, "/v1/reviews/:id", app.requireActivatedUser(app.deleteMovieReviewHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/reviews/:id/revisions", app.listMovieReviewRevisionsHandler)
+
+	// Movies pages
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:imdb_id", app.showMovieHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:imdb_id/reviews", app.listMovieReviewsOfMovieHandler)
+
+	// Home timeline of the reviews written by the followed users
+	router.HandlerFunc(http.MethodGet, "/v1/feed", app.requireAuthenticatedUser(app.showFeedHandler))
+
+	// movie Reviews reactions, API keys have no scope for them
+	router.HandlerFunc(http.MethodGet, "/v1/reviews/:id/reactions", app.listMovieReviewReactionsHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/reviews/:id/reactions/:type", app.requireActivatedUser(app.requireSessionUser(app.addMovieReviewReactionHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/reviews/:id/reactions/:type", app.requireActivatedUser(app.requireSessionUser(app.removeMovieReviewReactionHandler)))
+
+	// movie Reviews replies, answering a reply requires the same permission as writing a review
+	router.HandlerFunc(http.MethodGet, "/v1/reviews/:id/replies", app.listReviewRepliesHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/reviews/:id/replies", app.requirePermission(permissions.ReviewsWrite, app.createReviewReplyHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/replies/:id", app.requirePermission(permissions.ReviewsWrite, app.updateReviewReplyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/replies/:id", app.requireActivatedUser(app.deleteReviewReplyHandler))
 
 	// Users signup and sign-in
 	router.HandlerFunc(http.MethodPost, "/v1/users/auth/signup", app.registerUserHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/users/auth/signin", app.signInUserHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/users/auth/signin/2fa", app.twoFactorSignInHandler)
+
+	// Users activation
+	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+
+	// Users password reset
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/password-reset", app.createPasswordResetTokenHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/users/password", app.resetUserPasswordHandler)
+
+	// Users email change confirmation
+	router.HandlerFunc(http.MethodPut, "/v1/users/email", app.confirmEmailChangeHandler)
+
+	// Users profiles
+	router.HandlerFunc(http.MethodGet, "/v1/users/me", app.requireSessionUser(app.showCurrentUserHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me", app.requireSessionUser(app.updateCurrentUserHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me", app.requireSessionUser(app.deleteCurrentUserHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/by-handle/:handle", app.showUserProfileHandler)
+
+	// Users two-factor authentication
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/2fa", app.requireSessionUser(app.enrollTwoFactorHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/2fa", app.requireSessionUser(app.verifyTwoFactorHandler))
+
+	// Users API keys
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/api-keys", app.requireSessionUser(app.listAPIKeysHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/api-keys", app.requireSessionUser(app.createAPIKeyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/api-keys/:id", app.requireSessionUser(app.deleteAPIKeyHandler))
+
+	// Users follows. httprouter doesn't allow a "/v1/users/:id" wildcard next to the static
+	// "/v1/users/..." routes, so the routes about a given user live under "/v1/profiles/:id".
+	// API keys have no scope for the follows, so they can only be managed with a session
+	router.HandlerFunc(http.MethodPost, "/v1/profiles/:id/follow", app.requireActivatedUser(app.requireSessionUser(app.followUserHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/profiles/:id/follow", app.requireActivatedUser(app.requireSessionUser(app.unfollowUserHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/profiles/:id/followers", app.listFollowersHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/profiles/:id/following", app.listFollowingHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/follow-requests", app.requireSessionUser(app.listFollowRequestsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/follow-requests/:id", app.requireActivatedUser(app.requireSessionUser(app.acceptFollowRequestHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/follow-requests/:id", app.requireActivatedUser(app.requireSessionUser(app.rejectFollowRequestHandler)))
+
+	// Users permissions administration
+	router.HandlerFunc(http.MethodGet, "/v1/profiles/:id/permissions", app.requirePermission(permissions.UsersAdmin, app.listUserPermissionsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/profiles/:id/permissions", app.requirePermission(permissions.UsersAdmin, app.grantUserPermissionsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/profiles/:id/permissions/:code", app.requirePermission(permissions.UsersAdmin, app.revokeUserPermissionHandler))
 
-	return app.recoverPanic(app.rateLimit(router))
+	return app.recoverPanic(app.rateLimit(app.authenticate(router)))
 }
@@ -24,6 +24,14 @@ func (app *application) serve() error {
 
 	shutdownError := make(chan error)
 
+	// The background jobs run until the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	app.backgroundTask(func() {
+		app.purgeDeletedAccounts(jobsCtx)
+	})
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 
@@ -40,6 +48,7 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 		app.logger.Info("completing background tasks", "addr", srv.Addr)
+		stopJobs()
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/data/tokens"
+	"cinepulse.nlt.net/internal/data/users"
+	"cinepulse.nlt.net/internal/data/users/inputs"
+	"cinepulse.nlt.net/internal/mailer"
+	"cinepulse.nlt.net/internal/mailer/types"
+	"cinepulse.nlt.net/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Handler for "POST /v1/tokens/password-reset" endpoint
+func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.RequestPasswordResetInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateRequestPasswordResetInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// We always send the same response, whether a matching account exists or not, so
+	// that this endpoint can't be used to find out who has a CinePulse account
+	user, err := app.models.Users.GetByEmailOrId(users.Email, input.Email)
+	switch {
+	case err == nil:
+		if user.IsActivated {
+			app.backgroundTask(func() {
+				app.sendPasswordResetToken(user)
+			})
+		}
+	case errors.Is(err, shared.ErrRecordNotFound):
+	default:
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"message": "if an activated account exists for this email address, you will receive an email containing password reset instructions"}
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// sendPasswordResetToken issues a password reset token for the user and emails it to them
+func (app *application) sendPasswordResetToken(user *users.User) {
+	token, err := app.models.Tokens.New(user.ID, 45*time.Minute, tokens.ScopePasswordReset)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	var data = types.PasswordResetTemplateData{
+		ProfileHandle:      user.ProfileHandle,
+		PasswordResetLink:  fmt.Sprintf("https://cinepulse.nlt.net/users/password-reset?token=%s", token.Plaintext),
+		PasswordResetToken: token.Plaintext,
+		CurrentYear:        time.Now().Year(),
+	}
+
+	err = app.mailer.Send(user.Email, mailer.PasswordResetTemplate, data)
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+}
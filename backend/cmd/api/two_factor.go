@@ -0,0 +1,229 @@
+package main
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/data/tokens"
+	"cinepulse.nlt.net/internal/data/users"
+	"cinepulse.nlt.net/internal/data/users/inputs"
+	usersShared "cinepulse.nlt.net/internal/data/users/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Handler for "POST /v1/users/me/2fa" endpoint
+func (app *application) enrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.EnrollTwoFactorInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateEnrollTwoFactorInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The password is asked again so that a stolen authentication token can't be used to lock the
+	// user out of their account
+	user := app.contextGetUser(r)
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	secret := usersShared.GenerateTOTPSecret()
+
+	err = app.models.Users.SetTOTPSecret(user.ID, secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrTwoFactorAlreadyEnabled):
+			app.conflictResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := envelope{"two_factor": envelope{
+		"otpauth_uri": usersShared.TOTPKeyURI(secret, user.Email),
+		"secret":      usersShared.EncodeTOTPSecret(secret),
+	}}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "PUT /v1/users/me/2fa" endpoint
+func (app *application) verifyTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.VerifyTwoFactorInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateVerifyTwoFactorInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	if user.TOTPEnabled {
+		app.conflictResponse(w, r, users.ErrTwoFactorAlreadyEnabled)
+		return
+	}
+
+	secret, err := app.models.Users.GetTOTPSecret(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrTwoFactorNotEnrolled):
+			v.AddError("code", "two-factor authentication enrollment must be started first")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	step, ok := usersShared.MatchTOTPCode(secret, input.Code, time.Now())
+	if !ok {
+		v.AddError("code", "invalid or expired code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The recovery codes are only stored hashed, so this is the only time the user can see them
+	recoveryCodes := usersShared.GenerateRecoveryCodes(10)
+
+	err = app.models.Users.EnableTOTP(user, step, recoveryCodes)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrEditConflict):
+			app.conflictResponse(w, r, errors.New("unable to update the record due to an edit conflict, please try again"))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user, "recovery_codes": recoveryCodes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "POST /v1/users/auth/signin/2fa" endpoint
+func (app *application) twoFactorSignInHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.TwoFactorSignInInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateTwoFactorSignInInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(tokens.ScopeTwoFactor, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The two-factor token only allows a single attempt, which makes guessing the codes
+	// require the password for each try
+	err = app.models.Tokens.DeleteAllForUser(tokens.ScopeTwoFactor, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var verified bool
+	if input.Code != "" {
+		verified, err = app.checkTOTPCode(user.ID, input.Code)
+	} else {
+		verified, err = app.checkRecoveryCode(user.ID, input.RecoveryCode)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !verified {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	// Signing in cancels the deletion of the account
+	err = app.models.Users.CancelDeletion(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Authentication tokens are valid for 24 hours
+	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, tokens.ScopeAuthentication)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkTOTPCode reports whether the code is valid for the user and wasn't used before
+func (app *application) checkTOTPCode(userID int64, code string) (bool, error) {
+	secret, err := app.models.Users.GetTOTPSecret(userID)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := usersShared.MatchTOTPCode(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return app.models.Users.RecordTOTPStep(userID, step)
+}
+
+// checkRecoveryCode reports whether the recovery code is one of the user's, consuming it if so
+func (app *application) checkRecoveryCode(userID int64, code string) (bool, error) {
+	err := app.models.Users.UseRecoveryCode(userID, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"cinepulse.nlt.net/internal/data/permissions"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/data/tokens"
 	"cinepulse.nlt.net/internal/data/users"
 	"cinepulse.nlt.net/internal/data/users/inputs"
 	"cinepulse.nlt.net/internal/mailer"
 	"cinepulse.nlt.net/internal/mailer/types"
 	"cinepulse.nlt.net/internal/validator"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -51,10 +56,18 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
+	// The activation token is valid for 3 days, after which the user will need to request a new one
+	token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, tokens.ScopeActivation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	var data = types.UserWelcomeTemplateData{
-		ProfileHandle:  user.ProfileHandle,
-		ActivationLink: "https://cinepulse.nlt.net/users/activation/token=dfgdjfhdjfhdjfhdj",
-		CurrentYear:    time.Now().Year(),
+		ProfileHandle:   user.ProfileHandle,
+		ActivationLink:  fmt.Sprintf("https://cinepulse.nlt.net/users/activation?token=%s", token.Plaintext),
+		ActivationToken: token.Plaintext,
+		CurrentYear:     time.Now().Year(),
 	}
 
 	app.backgroundTask(func() {
@@ -69,3 +82,473 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// Handler for "PUT /v1/users/activated" endpoint
+func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.ActivateUserInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateActivateUserInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(tokens.ScopeActivation, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired activation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Users.Activate(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrEditConflict):
+			app.conflictResponse(w, r, errors.New("unable to update the record due to an edit conflict, please try again"))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Permissions.AddForUser(user.ID, permissions.Default...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The activation tokens are single-use, so we get rid of all of them for this user
+	err = app.models.Tokens.DeleteAllForUser(tokens.ScopeActivation, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "POST /v1/users/auth/signin" endpoint
+func (app *application) signInUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.SignInUserInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateSignInUserInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmailOrId(users.Email, input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	// With two-factor authentication, the client gets a short-lived token to send along with the
+	// code to "POST /v1/users/auth/signin/2fa" instead of the authentication token
+	if user.TOTPEnabled {
+		token, err := app.models.Tokens.New(user.ID, 5*time.Minute, tokens.ScopeTwoFactor)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env := envelope{"two_factor_token": token, "message": "a two-factor authentication code is required to complete the sign-in"}
+
+		err = app.writeJSON(w, http.StatusAccepted, env, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Signing in cancels the deletion of the account
+	err = app.models.Users.CancelDeletion(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Authentication tokens are valid for 24 hours
+	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, tokens.ScopeAuthentication)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "GET /v1/users/me" endpoint
+func (app *application) showCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	userPermissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user, "permissions": userPermissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "PATCH /v1/users/me" endpoint
+func (app *application) updateCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.UpdateUserInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateUpdateUserInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	// A new email address isn't applied right away, it only becomes pending until it is confirmed
+	// with the token sent to it. This way a stolen session can't be used to take over the account
+	var newEmail string
+	if input.Email != nil && !strings.EqualFold(*input.Email, currentUser.Email) {
+		newEmail = *input.Email
+	}
+	input.Email = nil
+
+	var (
+		user  *users.User
+		token *tokens.Token
+	)
+	switch {
+	case newEmail != "":
+		// The email change token is valid for 24 hours, after which the user will need to ask again
+		token = tokens.Generate(currentUser.ID, 24*time.Hour, tokens.ScopeEmailChange)
+		user, err = app.models.Users.RequestEmailChange(&input, currentUser, newEmail, token)
+	case input.ProfileHandle != nil || input.Location != nil || input.IsProtected != nil:
+		// The version of the user was read when authenticating the request, so the update fails
+		// with an edit conflict if the user has been modified in the meantime
+		user, err = app.models.Users.Update(&input, currentUser.Version, currentUser.ID)
+	default:
+		user = currentUser
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrDuplicateEmail):
+			v.AddError("email", "a user with this Email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, users.ErrDuplicateProfileHandle):
+			v.AddError("profile_handle", "a user with this ProfileHandle already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, shared.ErrEditConflict):
+			app.conflictResponse(w, r, errors.New("unable to update the record due to an edit conflict, please try again"))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := envelope{"user": user}
+	if newEmail != "" {
+		app.backgroundTask(func() {
+			app.sendEmailChangeEmails(user, newEmail, token)
+		})
+		env["pending_email"] = newEmail
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "GET /v1/users/by-handle/:handle" endpoint
+func (app *application) showUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	handle := app.readHandleParam(r)
+
+	profile, err := app.models.Users.GetPublicProfileByHandle(handle)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"profile": profile}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "PUT /v1/users/password" endpoint
+func (app *application) resetUserPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.ResetUserPasswordInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateResetUserPasswordInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(tokens.ScopePasswordReset, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired password reset token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = user.Password.Set(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Users.UpdatePassword(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrEditConflict):
+			app.conflictResponse(w, r, errors.New("unable to update the record due to an edit conflict, please try again"))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The reset token is single-use, and whoever was signed in with the old password is signed out
+	for _, scope := range []tokens.Scope{tokens.ScopePasswordReset, tokens.ScopeAuthentication} {
+		err = app.models.Tokens.DeleteAllForUser(scope, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// A reset usually follows a compromise of the account, and API keys are credentials as well: they
+	// are all revoked rather than trusting the ones that might have been created by an attacker
+	err = app.models.APIKeys.DeleteAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your password was successfully reset"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// sendEmailChangeEmails sends the confirmation token to the new email address of the user, and lets
+// the current address know that a change was requested
+func (app *application) sendEmailChangeEmails(user *users.User, newEmail string, token *tokens.Token) {
+	var data = types.EmailChangeTemplateData{
+		ProfileHandle:    user.ProfileHandle,
+		NewEmail:         newEmail,
+		EmailChangeLink:  fmt.Sprintf("https://cinepulse.nlt.net/users/email-change?token=%s", token.Plaintext),
+		EmailChangeToken: token.Plaintext,
+		CurrentYear:      time.Now().Year(),
+	}
+
+	err := app.mailer.Send(newEmail, mailer.EmailChangeTemplate, data)
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+
+	var noticeData = types.EmailChangeNoticeTemplateData{
+		ProfileHandle: user.ProfileHandle,
+		NewEmail:      newEmail,
+		CurrentYear:   time.Now().Year(),
+	}
+
+	err = app.mailer.Send(user.Email, mailer.EmailChangeNoticeTemplate, noticeData)
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+}
+
+// Handler for "PUT /v1/users/email" endpoint
+func (app *application) confirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.ConfirmEmailChangeInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateConfirmEmailChangeInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(tokens.ScopeEmailChange, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired email change token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Users.ConfirmEmailChange(user, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			v.AddError("token", "no email change is pending for this token")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, users.ErrDuplicateEmail):
+			v.AddError("email", "a user with this Email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, shared.ErrEditConflict):
+			app.conflictResponse(w, r, errors.New("unable to update the record due to an edit conflict, please try again"))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Password reset tokens were sent to the previous address, which doesn't own the account anymore
+	for _, scope := range []tokens.Scope{tokens.ScopeEmailChange, tokens.ScopePasswordReset} {
+		err = app.models.Tokens.DeleteAllForUser(scope, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Handler for "DELETE /v1/users/me" endpoint
+func (app *application) deleteCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input inputs.DeleteUserInput
+
+	err := app.readJSON(w, r, &input, 1024)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if inputs.ValidateDeleteUserInput(v, &input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	err = app.models.Users.ScheduleDeletion(user, time.Now().Add(app.config.deletion.gracePeriod))
+	if err != nil {
+		switch {
+		case errors.Is(err, shared.ErrEditConflict):
+			app.conflictResponse(w, r, errors.New("unable to update the record due to an edit conflict, please try again"))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The user is signed out everywhere, signing in again is what cancels the deletion
+	err = app.models.Tokens.DeleteAllForUser(tokens.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var data = types.DeletionScheduledTemplateData{
+		ProfileHandle: user.ProfileHandle,
+		DeletionDate:  user.DeletionScheduledAt.Format("January 2, 2006"),
+		CurrentYear:   time.Now().Year(),
+	}
+
+	app.backgroundTask(func() {
+		err := app.mailer.Send(user.Email, mailer.DeletionScheduledTemplate, data)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
+	env := envelope{
+		"message":               "your account is scheduled for deletion, sign in again before that date to cancel it",
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
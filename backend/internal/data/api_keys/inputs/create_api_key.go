@@ -0,0 +1,31 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/data/permissions"
+	"cinepulse.nlt.net/internal/validator"
+	"time"
+	"unicode/utf8"
+)
+
+type CreateAPIKeyInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"` // Keys without expiry stay valid until deleted
+}
+
+// ValidateCreateAPIKeyInput checks the input against the permissions of the user, as a key can't
+// be given more rights than its owner
+func ValidateCreateAPIKeyInput(v *validator.Validator, input *CreateAPIKeyInput, userPermissions permissions.Permissions) {
+	v.RequiredString(input.Name, "name")
+	v.AddErrorIfNot(utf8.RuneCountInString(input.Name) <= 100, "name", "must not have more than 100 characters")
+
+	v.AddErrorIfNot(len(input.Scopes) > 0, "scopes", "must contain at least one permission")
+	for _, scope := range input.Scopes {
+		v.AddErrorIfNot(validator.PermittedValue(scope, permissions.All...), "scopes", "unknown permission "+scope)
+		v.AddErrorIfNot(userPermissions.Include(scope), "scopes", "must only contain permissions that you were granted")
+	}
+
+	if input.ExpiresAt != nil {
+		v.AddErrorIfNot(input.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+}
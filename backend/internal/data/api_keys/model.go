@@ -0,0 +1,192 @@
+package api_keys
+
+import (
+	"cinepulse.nlt.net/internal/data/permissions"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/data/tokens"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+// KeyPrefix starts every API key, which tells them apart from the session tokens in the
+// Authorization header and makes leaked keys easy to spot
+const KeyPrefix = "cpk_"
+
+// visiblePrefixLength is the number of characters of a key that are stored in clear, so that
+// users can recognise their keys
+const visiblePrefixLength = len(KeyPrefix) + 8
+
+// APIKey is a long-lived credential of a user. Its scopes are the permissions it is restricted to
+type APIKey struct {
+	ID         int64                   `json:"id"`
+	UserID     int64                   `json:"-"`
+	Name       string                  `json:"name"`
+	Plaintext  string                  `json:"key,omitempty"` // Only set when the key is created
+	Prefix     string                  `json:"prefix"`
+	Scopes     permissions.Permissions `json:"scopes"`
+	CreatedAt  time.Time               `json:"created_at"`
+	LastUsedAt *time.Time              `json:"last_used_at"`
+	ExpiresAt  *time.Time              `json:"expires_at"`
+}
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// IsAPIKey reports whether the credential sent by a client looks like an API key
+func IsAPIKey(plaintext string) bool {
+	return strings.HasPrefix(plaintext, KeyPrefix)
+}
+
+// New generates an API key for the user and stores its hash. The plaintext key is only available
+// on the returned value
+func (m APIKeyModel) New(userID int64, name string, scopes []string, expiresAt *time.Time) (*APIKey, error) {
+	plaintext := KeyPrefix + rand.Text()
+
+	key := &APIKey{
+		UserID:    userID,
+		Name:      name,
+		Plaintext: plaintext,
+		Prefix:    plaintext[:visiblePrefixLength],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+
+	query := `
+         INSERT INTO api_keys (user_id, name, prefix, hash, scopes, expires_at)
+         VALUES ($1, $2, $3, $4, $5, $6)
+         RETURNING id, created_at`
+
+	args := []any{key.UserID, key.Name, key.Prefix, tokens.Hash(plaintext), pq.Array(key.Scopes), key.ExpiresAt}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAllForUser lists the API keys of the user, the most recent first
+func (m APIKeyModel) GetAllForUser(userID int64) (keys []*APIKey, err error) {
+	query := `
+         SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, expires_at
+         FROM api_keys
+         WHERE user_id = $1
+         ORDER BY created_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	keys = []*APIKey{}
+	for rows.Next() {
+		var key APIKey
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			pq.Array(&key.Scopes),
+			&key.CreatedAt,
+			&key.LastUsedAt,
+			&key.ExpiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Authenticate returns the non-expired API key matching the plaintext, and records that it was used
+func (m APIKeyModel) Authenticate(plaintext string) (*APIKey, error) {
+	query := `
+         UPDATE api_keys
+         SET last_used_at = now()
+         WHERE hash = $1 AND (expires_at IS NULL OR expires_at > now())
+         RETURNING id, user_id, name, prefix, scopes, created_at, last_used_at, expires_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var key APIKey
+	err := m.DB.QueryRowContext(ctx, query, tokens.Hash(plaintext)).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Scopes),
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.ExpiresAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, shared.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &key, nil
+}
+
+// Delete revokes an API key of the user
+func (m APIKeyModel) Delete(id, userID int64) error {
+	query := `
+         DELETE FROM api_keys
+         WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteAllForUser revokes all the API keys of the user
+func (m APIKeyModel) DeleteAllForUser(userID int64) error {
+	query := `
+         DELETE FROM api_keys
+         WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
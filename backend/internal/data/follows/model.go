@@ -0,0 +1,275 @@
+package follows
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSelfFollow          = errors.New("users cannot follow themselves")
+	RequestTimeOutDuration = 3 * time.Second
+)
+
+// FollowStatus describes the relationship between a user and a user they asked to follow
+type FollowStatus = string
+
+const (
+	Following FollowStatus = "following" // The follow is effective
+	Requested FollowStatus = "requested" // The followed user is protected and has to accept the request
+)
+
+// FollowUser is a user appearing in a followers, following or follow requests list
+type FollowUser struct {
+	ID     int64     `json:"id"`
+	Handle string    `json:"handle"`
+	Since  time.Time `json:"since"`
+}
+
+type FollowModel struct {
+	DB *sql.DB
+}
+
+// Follow makes followerID follow targetID. Public accounts are followed right away while protected
+// ones receive a follow request. Following someone twice is a no-op
+func (m FollowModel) Follow(followerID, targetID int64) (FollowStatus, error) {
+	if followerID == targetID {
+		return "", ErrSelfFollow
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Lock the target so that its protection can't change while we are following it
+	var isProtected bool
+	err = tx.QueryRowContext(ctx, "SELECT is_protected FROM users WHERE id = $1 FOR SHARE", targetID).Scan(&isProtected)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", shared.ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	var alreadyFollowing bool
+	err = tx.QueryRowContext(ctx, `
+         SELECT EXISTS (SELECT 1 FROM user_followings WHERE follower_id = $1 AND following_id = $2)`,
+		followerID, targetID).Scan(&alreadyFollowing)
+	if err != nil {
+		return "", err
+	}
+
+	status := Following
+	switch {
+	case alreadyFollowing:
+		return Following, nil
+	case isProtected:
+		status = Requested
+		_, err = tx.ExecContext(ctx, `
+         INSERT INTO follow_requests (requester_id, target_id)
+         VALUES ($1, $2)
+         ON CONFLICT DO NOTHING`, followerID, targetID)
+	default:
+		_, err = tx.ExecContext(ctx, `
+         INSERT INTO user_followings (follower_id, following_id)
+         VALUES ($1, $2)
+         ON CONFLICT DO NOTHING`, followerID, targetID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return status, tx.Commit()
+}
+
+// Unfollow stops followerID from following targetID, and cancels any pending follow request
+func (m FollowModel) Unfollow(followerID, targetID int64) error {
+	query := `
+         WITH deleted_following AS (
+             DELETE FROM user_followings
+             WHERE follower_id = $1 AND following_id = $2
+             RETURNING 1
+         ), deleted_request AS (
+             DELETE FROM follow_requests
+             WHERE requester_id = $1 AND target_id = $2
+             RETURNING 1
+         )
+         SELECT (SELECT count(*) FROM deleted_following) + (SELECT count(*) FROM deleted_request)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	var deleted int
+	err := m.DB.QueryRowContext(ctx, query, followerID, targetID).Scan(&deleted)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AcceptFollowRequest turns the pending request of requesterID into an effective follow of targetID
+func (m FollowModel) AcceptFollowRequest(targetID, requesterID int64) error {
+	query := `
+         WITH accepted AS (
+             DELETE FROM follow_requests
+             WHERE requester_id = $1 AND target_id = $2
+             RETURNING requester_id, target_id
+         ), inserted AS (
+             INSERT INTO user_followings (follower_id, following_id)
+             SELECT requester_id, target_id FROM accepted
+             ON CONFLICT DO NOTHING
+         )
+         SELECT count(*) FROM accepted`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	var accepted int
+	err := m.DB.QueryRowContext(ctx, query, requesterID, targetID).Scan(&accepted)
+	if err != nil {
+		return err
+	}
+	if accepted == 0 {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RejectFollowRequest deletes the pending request of requesterID to follow targetID
+func (m FollowModel) RejectFollowRequest(targetID, requesterID int64) error {
+	query := `
+         DELETE FROM follow_requests
+         WHERE requester_id = $1 AND target_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, requesterID, targetID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CanViewFollows reports whether viewerID may see who userID follows and is followed by. The
+// lists of protected users are only visible to themselves and to their followers
+func (m FollowModel) CanViewFollows(viewerID, userID int64) (bool, error) {
+	query := `
+         SELECT u.is_protected = FALSE
+                OR u.id = $2
+                OR EXISTS (SELECT 1 FROM user_followings f WHERE f.follower_id = $2 AND f.following_id = u.id)
+         FROM users u
+         WHERE u.id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	var canView bool
+	err := m.DB.QueryRowContext(ctx, query, userID, viewerID).Scan(&canView)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return false, shared.ErrRecordNotFound
+		default:
+			return false, err
+		}
+	}
+	return canView, nil
+}
+
+// GetFollowers lists the users following userID, the most recent first
+func (m FollowModel) GetFollowers(userID int64, queryInput *shared.PageInput) ([]*FollowUser, shared.Metadata, error) {
+	fromClause := `
+         FROM user_followings f
+         INNER JOIN users u ON u.id = f.follower_id
+         WHERE f.following_id = $1`
+
+	return m.list(fromClause, "f.created_at", "DESC", userID, queryInput)
+}
+
+// GetFollowing lists the users followed by userID, the most recent first
+func (m FollowModel) GetFollowing(userID int64, queryInput *shared.PageInput) ([]*FollowUser, shared.Metadata, error) {
+	fromClause := `
+         FROM user_followings f
+         INNER JOIN users u ON u.id = f.following_id
+         WHERE f.follower_id = $1`
+
+	return m.list(fromClause, "f.created_at", "DESC", userID, queryInput)
+}
+
+// GetFollowRequests lists the users waiting for userID to accept their follow request, the oldest first
+func (m FollowModel) GetFollowRequests(userID int64, queryInput *shared.PageInput) ([]*FollowUser, shared.Metadata, error) {
+	fromClause := `
+         FROM follow_requests fr
+         INNER JOIN users u ON u.id = fr.requester_id
+         WHERE fr.target_id = $1`
+
+	return m.list(fromClause, "fr.created_at", "ASC", userID, queryInput)
+}
+
+// list runs a paginated query over users. fromClause must alias the users table as "u" and filter
+// on $1, sinceColumn is the date at which the relationship started and is sorted in the given direction
+func (m FollowModel) list(fromClause, sinceColumn, direction string, userID int64, queryInput *shared.PageInput) (followUsers []*FollowUser, metadata shared.Metadata, err error) {
+	query := `
+         SELECT count(*) OVER(), u.id, u.handle, ` + sinceColumn + fromClause + `
+         ORDER BY ` + sinceColumn + ` ` + direction + `, u.id
+         LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, queryInput.Limit(), queryInput.Offset())
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	followUsers = []*FollowUser{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var followUser FollowUser
+		err := rows.Scan(&totalRecords, &followUser.ID, &followUser.Handle, &followUser.Since)
+		if err != nil {
+			return nil, shared.Metadata{}, err
+		}
+		followUsers = append(followUsers, &followUser)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	totalRecords, err = shared.CountRecords(ctx, m.DB, totalRecords, len(followUsers), *queryInput, fromClause, userID)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	metadata = shared.CalculateMetadata(totalRecords, queryInput.Page, queryInput.PageSize)
+	return followUsers, metadata, nil
+}
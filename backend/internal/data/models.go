@@ -1,19 +1,34 @@
 package data
 
 import (
+	"cinepulse.nlt.net/internal/data/api_keys"
+	"cinepulse.nlt.net/internal/data/follows"
 	"cinepulse.nlt.net/internal/data/movie_reviews"
+	"cinepulse.nlt.net/internal/data/movies"
+	"cinepulse.nlt.net/internal/data/permissions"
+	"cinepulse.nlt.net/internal/data/tokens"
 	"cinepulse.nlt.net/internal/data/users"
 	"database/sql"
 )
 
 type Models struct {
+	APIKeys      api_keys.APIKeyModel
+	Follows      follows.FollowModel
 	MovieReviews movie_reviews.MovieReviewModel
+	Movies       movies.MovieModel
+	Permissions  permissions.PermissionModel
+	Tokens       tokens.TokenModel
 	Users        users.UserModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
+		APIKeys:      api_keys.APIKeyModel{DB: db},
+		Follows:      follows.FollowModel{DB: db},
 		MovieReviews: movie_reviews.MovieReviewModel{DB: db},
+		Movies:       movies.MovieModel{DB: db},
+		Permissions:  permissions.PermissionModel{DB: db},
+		Tokens:       tokens.TokenModel{DB: db},
 		Users:        users.UserModel{DB: db},
 	}
 }
@@ -0,0 +1,77 @@
+package movie_reviews
+
+import (
+	"cinepulse.nlt.net/internal/data/movie_reviews/inputs"
+	"cinepulse.nlt.net/internal/data/shared"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetFeed returns the reviews written by the users viewerID follows, newest first, using keyset
+// pagination on (created_at, id). Following a protected user requires them to accept the follow
+// request, so every review of the feed is visible to the viewer
+func (m MovieReviewModel) GetFeed(viewerID int64, after *shared.Cursor, queryInput *inputs.FeedQueryInput) (reviews []*MovieReview, metadata shared.Metadata, next *shared.Cursor, err error) {
+	args := []any{viewerID}
+	cursorCondition := ""
+	if after != nil {
+		args = append(args, after.Time, after.ID)
+		cursorCondition = "AND (mr.created_at, mr.id) < ($2, $3)"
+	}
+	// We fetch one extra review to know whether there is a next page
+	args = append(args, queryInput.Limit()+1)
+
+	query := fmt.Sprintf(`
+        SELECT `+movieReviewColumns+`
+        FROM movie_reviews mr
+        INNER JOIN users u ON u.id = mr.user_id
+        INNER JOIN user_followings f ON f.following_id = mr.user_id AND f.follower_id = $1
+        WHERE TRUE %s
+        ORDER BY mr.created_at DESC, mr.id DESC
+        LIMIT $%d`, cursorCondition, len(args))
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, shared.Metadata{}, nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	reviews = []*MovieReview{}
+
+	for rows.Next() {
+		var review MovieReview
+
+		err := rows.Scan(review.scanDestinations()...)
+		if err != nil {
+			return nil, shared.Metadata{}, nil, err
+		}
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, shared.Metadata{}, nil, err
+	}
+
+	if len(reviews) > queryInput.Limit() {
+		reviews = reviews[:queryInput.Limit()]
+		last := reviews[len(reviews)-1]
+		next = &shared.Cursor{Kind: shared.FeedCursor, Time: last.CreatedAt, ID: last.ID}
+	}
+
+	err = m.populateReactions(ctx, viewerID, reviews...)
+	if err != nil {
+		return nil, shared.Metadata{}, nil, err
+	}
+
+	metadata = shared.CalculateCursorMetadata(queryInput.PageSize, after == nil, next != nil)
+	return reviews, metadata, next, nil
+}
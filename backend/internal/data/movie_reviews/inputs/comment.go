@@ -0,0 +1,13 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/validator"
+	"unicode/utf8"
+)
+
+// validateComment applies the rules shared by every text written by users about reviews, the
+// statement of a review as well as the replies to it
+func validateComment(v *validator.Validator, comment, key string) {
+	v.RequiredString(comment, key)
+	v.AddErrorIfNot(utf8.RuneCountInString(comment) <= 280, key, "must not have more than 280 characters")
+}
@@ -1,9 +1,8 @@
 package inputs
 
 import (
+	"cinepulse.nlt.net/internal/data/movies"
 	"cinepulse.nlt.net/internal/validator"
-	"strings"
-	"unicode/utf8"
 )
 
 type CreateMovieReviewInput struct {
@@ -13,9 +12,8 @@ type CreateMovieReviewInput struct {
 }
 
 func ValidateCreateMovieReviewInput(v *validator.Validator, input *CreateMovieReviewInput) {
-	v.RequiredString(strings.TrimSpace(input.ImdbID), "imdb_id")
+	movies.ValidateImdbID(v, input.ImdbID)
 	v.AddErrorIfNot(input.Rating >= 1, "rating", "must be greater than zero")
 	v.AddErrorIfNot(input.Rating <= 5, "rating", "must be at most equal to 5")
-	v.RequiredString(input.StatementComment, "statement_comment")
-	v.AddErrorIfNot(utf8.RuneCountInString(input.StatementComment) <= 280, "statement_comment", "must not have more than 280 characters")
+	validateComment(v, input.StatementComment, "statement_comment")
 }
@@ -0,0 +1,16 @@
+package inputs
+
+import "cinepulse.nlt.net/internal/validator"
+
+type FeedQueryInput struct {
+	PageSize int
+}
+
+func (i FeedQueryInput) Limit() int {
+	return i.PageSize
+}
+
+func ValidateFeedQueryInput(v *validator.Validator, input *FeedQueryInput) {
+	v.AddErrorIfNot(input.PageSize > 0, "page_size", "must be greater than zero")
+	v.AddErrorIfNot(input.PageSize <= 100, "page_size", "must be a maximum of 100")
+}
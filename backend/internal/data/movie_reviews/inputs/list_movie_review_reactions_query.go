@@ -0,0 +1,15 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+)
+
+type ListMovieReviewReactionsQueryInput struct {
+	Reaction string // Optional, all the reactions are listed when empty
+	shared.PageInput
+}
+
+func ValidateListMovieReviewReactionsQueryInput(v *validator.Validator, input *ListMovieReviewReactionsQueryInput) {
+	shared.ValidatePageInput(v, &input.PageInput)
+}
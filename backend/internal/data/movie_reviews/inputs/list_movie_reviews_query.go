@@ -1,23 +1,140 @@
 package inputs
 
-import "cinepulse.nlt.net/internal/validator"
+import (
+	"cinepulse.nlt.net/internal/data/movies"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ListMovieReviewsSortSafelist holds the values accepted for the "sort" query string parameter.
+// A leading "-" means a descending order
+var ListMovieReviewsSortSafelist = []string{
+	"id", "rating", "created_at", "updated_at", "reactions", "relevance",
+	"-id", "-rating", "-created_at", "-updated_at", "-reactions", "-relevance",
+}
+
+// sortColumns maps each sort value (without its direction) to the SQL expression used to sort.
+// The movie_reviews table is expected to be aliased as "mr"
+var sortColumns = map[string]string{
+	"id":         "mr.id",
+	"rating":     "mr.rating",
+	"created_at": "mr.created_at",
+	"updated_at": "mr.updated_at",
+	"reactions":  "(SELECT count(*) FROM movie_review_reactions r WHERE r.movie_review_id = mr.id)",
+	"relevance":  "search_rank", // Only selected when searching
+}
 
 type ListMovieReviewsQueryInput struct {
-	Page     int
-	PageSize int
+	// Filters, their zero value means that they are not applied
+	ImdbID        string
+	UserID        int64
+	Handle        string
+	MinRating     int
+	MaxRating     int
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// Full-text search over the statements, Highlight adds a snippet of the matching parts
+	Query     string
+	Highlight bool
+
+	Sort string
+	shared.PageInput
+	// CursorMode is set when paginating with a cursor rather than with page numbers
+	CursorMode bool
+}
+
+// SortColumn returns the SQL expression to sort on. It panics if the sort value isn't in the
+// safelist, which protects us against SQL injection should the validation ever be skipped
+func (i ListMovieReviewsQueryInput) SortColumn() string {
+	if !validator.PermittedValue(i.Sort, ListMovieReviewsSortSafelist...) {
+		panic("unsafe sort parameter: " + i.Sort)
+	}
+	return sortColumns[strings.TrimPrefix(i.Sort, "-")]
+}
+
+// SortDirection returns "ASC" or "DESC" depending on the prefix of the sort value
+func (i ListMovieReviewsQueryInput) SortDirection() string {
+	if strings.HasPrefix(i.Sort, "-") {
+		return "DESC"
+	}
+	return "ASC"
 }
 
-func (i ListMovieReviewsQueryInput) Limit() int {
-	return i.PageSize
+// OrderBy returns the full ORDER BY expression. The review ID is used as a tiebreaker so that
+// the order stays stable across pages when several reviews share the same sort value
+func (i ListMovieReviewsQueryInput) OrderBy() string {
+	column, direction := i.SortColumn(), i.SortDirection()
+	if column == sortColumns["id"] {
+		return column + " " + direction
+	}
+	return column + " " + direction + ", mr.id " + direction
 }
 
-func (i ListMovieReviewsQueryInput) Offset() int {
-	return (i.Page - 1) * i.PageSize
+// Filters returns the filters that are applied, keyed by their query string parameter name
+func (i ListMovieReviewsQueryInput) Filters() map[string]any {
+	filters := map[string]any{}
+	if i.Query != "" {
+		filters["q"] = i.Query
+	}
+	if i.ImdbID != "" {
+		filters["imdb_id"] = i.ImdbID
+	}
+	if i.UserID != 0 {
+		filters["user_id"] = i.UserID
+	}
+	if i.Handle != "" {
+		filters["handle"] = i.Handle
+	}
+	if i.MinRating != 0 {
+		filters["min_rating"] = i.MinRating
+	}
+	if i.MaxRating != 0 {
+		filters["max_rating"] = i.MaxRating
+	}
+	if i.CreatedAfter != nil {
+		filters["created_after"] = *i.CreatedAfter
+	}
+	if i.CreatedBefore != nil {
+		filters["created_before"] = *i.CreatedBefore
+	}
+	return filters
 }
 
 func ValidateListMovieReviewsQueryInput(v *validator.Validator, input *ListMovieReviewsQueryInput) {
-	v.AddErrorIfNot(input.Page > 0, "page", "must be greater than zero")
-	v.AddErrorIfNot(input.Page <= 10_000_000, "page", "must be a maximum of 10 million")
-	v.AddErrorIfNot(input.PageSize > 0, "page_size", "must be greater than zero")
-	v.AddErrorIfNot(input.PageSize <= 100, "page_size", "must be a maximum of 100")
+	shared.ValidatePageInput(v, &input.PageInput)
+
+	v.AddErrorIfNot(validator.PermittedValue(input.Sort, ListMovieReviewsSortSafelist...), "sort", "invalid sort value")
+	// Keyset pagination relies on the (updated_at, id) order
+	if input.CursorMode {
+		v.AddErrorIfNot(input.Sort == "-updated_at", "sort", "must be -updated_at when paginating with a cursor")
+	}
+	if strings.TrimPrefix(input.Sort, "-") == "relevance" {
+		v.AddErrorIfNot(input.Query != "", "sort", "relevance can only be used along with q")
+	}
+
+	v.AddErrorIfNot(utf8.RuneCountInString(input.Query) <= 200, "q", "must not have more than 200 characters")
+	v.AddErrorIfNot(input.Query != "" || !input.Highlight, "highlight", "can only be used along with q")
+
+	if input.ImdbID != "" {
+		movies.ValidateImdbID(v, input.ImdbID)
+	}
+	v.AddErrorIfNot(input.UserID >= 0, "user_id", "must be greater than zero")
+	v.AddErrorIfNot(len(input.Handle) <= 30, "handle", "must not be more than 30 bytes long")
+
+	if input.MinRating != 0 {
+		v.AddErrorIfNot(input.MinRating >= 1 && input.MinRating <= 5, "min_rating", "must be between 1 and 5")
+	}
+	if input.MaxRating != 0 {
+		v.AddErrorIfNot(input.MaxRating >= 1 && input.MaxRating <= 5, "max_rating", "must be between 1 and 5")
+	}
+	if input.MinRating != 0 && input.MaxRating != 0 {
+		v.AddErrorIfNot(input.MinRating <= input.MaxRating, "min_rating", "must not be greater than max_rating")
+	}
+	if input.CreatedAfter != nil && input.CreatedBefore != nil {
+		v.AddErrorIfNot(input.CreatedAfter.Before(*input.CreatedBefore), "created_after", "must be before created_before")
+	}
 }
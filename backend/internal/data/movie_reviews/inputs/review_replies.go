@@ -0,0 +1,23 @@
+package inputs
+
+import "cinepulse.nlt.net/internal/validator"
+
+type CreateReviewReplyInput struct {
+	ParentID *int64 `json:"parent_id"` // Optional, set when answering another reply rather than the review
+	Comment  string `json:"comment"`
+}
+
+type UpdateReviewReplyInput struct {
+	Comment string `json:"comment"`
+}
+
+func ValidateCreateReviewReplyInput(v *validator.Validator, input *CreateReviewReplyInput) {
+	if input.ParentID != nil {
+		v.AddErrorIfNot(*input.ParentID > 0, "parent_id", "must be greater than zero")
+	}
+	validateComment(v, input.Comment, "comment")
+}
+
+func ValidateUpdateReviewReplyInput(v *validator.Validator, input *UpdateReviewReplyInput) {
+	validateComment(v, input.Comment, "comment")
+}
@@ -1,9 +1,6 @@
 package inputs
 
-import (
-	"cinepulse.nlt.net/internal/validator"
-	"unicode/utf8"
-)
+import "cinepulse.nlt.net/internal/validator"
 
 type UpdateMovieReviewInput struct {
 	Rating           *int8   `json:"rating"`
@@ -17,7 +14,6 @@ func ValidateUpdateMovieReviewInput(v *validator.Validator, input *UpdateMovieRe
 	}
 
 	if input.StatementComment != nil {
-		v.RequiredString(*input.StatementComment, "statement_comment")
-		v.AddErrorIfNot(utf8.RuneCountInString(*input.StatementComment) <= 280, "statement_comment", "must not have more than 280 characters")
+		validateComment(v, *input.StatementComment, "statement_comment")
 	}
 }
@@ -2,18 +2,21 @@ package movie_reviews
 
 import (
 	"cinepulse.nlt.net/internal/data/movie_reviews/inputs"
+	"cinepulse.nlt.net/internal/data/movies"
 	"cinepulse.nlt.net/internal/data/shared"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
+	"html"
 	"strings"
 	"time"
 )
 
 var (
-	ErrDuplicateImdbID     = errors.New("duplicate imdb_id")
+	ErrDuplicateReview     = errors.New("duplicate review for the same user and imdb_id")
 	RequestTimeOutDuration = 3 * time.Second
 )
 
@@ -28,7 +31,8 @@ const (
 	WellSaid         MovieReviewReaction = "WellSaid"         //🙌
 )
 
-type MovieReviewReactionMap = map[MovieReviewReaction][]int64
+// MovieReviewReactionCounts holds the number of users who left each reaction on a review
+type MovieReviewReactionCounts = map[MovieReviewReaction]int64
 
 type MovieReviewStatement struct {
 	Comment   string    `json:"comment"`
@@ -36,15 +40,105 @@ type MovieReviewStatement struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// MovieReviewAuthor is the summary of the user who wrote a review
+type MovieReviewAuthor struct {
+	ID     int64  `json:"id"`
+	Handle string `json:"handle"`
+}
+
 type MovieReview struct {
-	ID        int64                   `json:"id"`
-	CreatedAt time.Time               `json:"created_at"`
-	UpdatedAt time.Time               `json:"updated_at"`
-	Reactions *MovieReviewReactionMap `json:"reactions"`
-	ImdbID    string                  `json:"imdb_id"`
-	Rating    int8                    `json:"rating"`
-	Statement MovieReviewStatement    `json:"statement"`
-	Version   int64                   `json:"version"` // This will be incremented every time the user edits any of the editable information about the review
+	ID        int64                     `json:"id"`
+	CreatedAt time.Time                 `json:"created_at"`
+	UpdatedAt time.Time                 `json:"updated_at"`
+	Author    MovieReviewAuthor         `json:"author"`
+	Reactions MovieReviewReactionCounts `json:"reactions"`
+	// The reactions left by the user making the request, empty for anonymous users
+	ViewerReactions []MovieReviewReaction `json:"viewer_reactions"`
+	ImdbID          string                `json:"imdb_id"`
+	Movie           *movies.Summary       `json:"movie"` // Nil until the movie is in the catalogue
+	Rating          int8                  `json:"rating"`
+	Statement       MovieReviewStatement  `json:"statement"`
+	Version         int64                 `json:"version"` // This will be incremented every time the user edits any of the editable information about the review
+	// Edited is set once the review has been changed after its creation, reactions may then predate the current content
+	Edited        bool  `json:"edited"`
+	RevisionCount int64 `json:"revision_count"` // The number of previous states kept in the edit history
+	// ReplyCount includes the answers to other replies. Like the movie aggregates, only the replies
+	// of public users are counted as the other ones are hidden from most viewers
+	ReplyCount int64 `json:"reply_count"`
+
+	// Only set when searching reviews
+	SearchRank *float32 `json:"search_rank,omitempty"`
+	Headline   string   `json:"headline,omitempty"` // Safe HTML: the statement is escaped, only the <mark> tags are markup
+}
+
+// movieReviewColumns are the columns needed to build a MovieReview, in the order expected by
+// scanDestinations(). Queries using them must alias movie_reviews as "mr" and users as "u"
+const movieReviewColumns = `mr.id, mr.imdb_id, mr.rating, mr.statement_comment, mr.statement_created_at,
+                mr.statement_updated_at, mr.created_at, mr.updated_at, mr.version, u.id, u.handle,
+                (SELECT json_build_object('title', mv.title, 'year', mv.year, 'poster_url', mv.poster_url)
+                 FROM movies mv WHERE mv.imdb_id = mr.imdb_id),
+                mr.version > 1, (SELECT count(*) FROM movie_review_revisions rv WHERE rv.movie_review_id = mr.id),
+                (SELECT count(*) FROM review_replies rp INNER JOIN users ru ON ru.id = rp.user_id
+                 WHERE rp.movie_review_id = mr.id AND ru.is_protected = FALSE)`
+
+// movieSummaryScanner scans the JSON movie summary selected by movieReviewColumns, which is NULL
+// when the movie isn't in the catalogue
+type movieSummaryScanner struct {
+	dst **movies.Summary
+}
+
+func (s movieSummaryScanner) Scan(src any) error {
+	var content []byte
+	switch src := src.(type) {
+	case nil:
+		*s.dst = nil
+		return nil
+	case []byte:
+		content = src
+	case string:
+		content = []byte(src)
+	default:
+		return fmt.Errorf("unexpected movie summary type %T", src)
+	}
+
+	var summary movies.Summary
+	err := json.Unmarshal(content, &summary)
+	if err != nil {
+		return err
+	}
+	*s.dst = &summary
+	return nil
+}
+
+// visibleToViewer returns the condition restricting reviews to the ones the viewer, whose ID is
+// the positional argument viewerArg, is allowed to see: reviews of protected users are only visible
+// to their author and to the users following them. Queries must alias users as "u"
+func visibleToViewer(viewerArg int) string {
+	return fmt.Sprintf(`(
+             u.is_protected = FALSE
+             OR u.id = $%[1]d
+             OR EXISTS (SELECT 1 FROM user_followings vf WHERE vf.follower_id = $%[1]d AND vf.following_id = u.id)
+         )`, viewerArg)
+}
+
+func (review *MovieReview) scanDestinations() []any {
+	return []any{
+		&review.ID,
+		&review.ImdbID,
+		&review.Rating,
+		&review.Statement.Comment,
+		&review.Statement.CreatedAt,
+		&review.Statement.UpdatedAt,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+		&review.Version,
+		&review.Author.ID,
+		&review.Author.Handle,
+		movieSummaryScanner{dst: &review.Movie},
+		&review.Edited,
+		&review.RevisionCount,
+		&review.ReplyCount,
+	}
 }
 
 type CreatedMovieReview struct {
@@ -57,49 +151,90 @@ type MovieReviewModel struct {
 	DB *sql.DB
 }
 
-func (m MovieReviewModel) Insert(review *inputs.CreateMovieReviewInput) (*CreatedMovieReview, error) {
-	// TODO add user ID here
+func (m MovieReviewModel) Insert(review *inputs.CreateMovieReviewInput, userID int64) (*CreatedMovieReview, error) {
 	query := `
          INSERT INTO movie_reviews (
+                                    user_id,
                                     imdb_id,
                                     rating,
                                     statement_comment
          )
-         VALUES ($1, $2, $3)
+         VALUES ($1, $2, $3, $4)
          RETURNING id, created_at, version;
    `
 	var result CreatedMovieReview
-	args := []any{review.ImdbID, review.Rating, review.StatementComment}
+	args := []any{userID, review.ImdbID, review.Rating, review.StatementComment}
 	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
 	defer cancel()
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&result.ID, &result.CreatedAt, &result.Version)
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return &CreatedMovieReview{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&result.ID, &result.CreatedAt, &result.Version)
 	if err != nil {
 		var pqErr *pq.Error
 		switch {
-		case errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation":
-			return &CreatedMovieReview{}, ErrDuplicateImdbID
+		case errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == "unique_user_imdb_id":
+			return &CreatedMovieReview{}, ErrDuplicateReview
 		default:
 			return &CreatedMovieReview{}, err
 		}
 	}
+
+	err = adjustStats(ctx, tx, review.ImdbID, userID, review.Rating, 1)
+	if err != nil {
+		return &CreatedMovieReview{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return &CreatedMovieReview{}, err
+	}
 	return &result, nil
 }
 
-func (m MovieReviewModel) GetVersionFor(id int64) (int64, error) {
+// GetVersionAndAuthorFor returns the current version of a review along with the ID of its author.
+// It is used to check ownership before editing or deleting a review
+func (m MovieReviewModel) GetVersionAndAuthorFor(id int64) (version, authorID int64, err error) {
 	if id < 1 {
-		return 0, shared.ErrRecordNotFound
+		return 0, 0, shared.ErrRecordNotFound
 	}
 
 	query := `
-         SELECT version
+         SELECT version, user_id
          FROM movie_reviews
          WHERE id = $1;`
 
-	var version int64
 	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(&version)
+	err = m.DB.QueryRowContext(ctx, query, id).Scan(&version, &authorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, 0, shared.ErrRecordNotFound
+		default:
+			return 0, 0, err
+		}
+	}
+	return version, authorID, nil
+}
+
+// GetIDForAuthorAndImdbID returns the ID of the review a user wrote for a given movie
+func (m MovieReviewModel) GetIDForAuthorAndImdbID(userID int64, imdbID string) (int64, error) {
+	query := `
+         SELECT id
+         FROM movie_reviews
+         WHERE user_id = $1 AND imdb_id = $2;`
+
+	var id int64
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, imdbID).Scan(&id)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -108,37 +243,30 @@ func (m MovieReviewModel) GetVersionFor(id int64) (int64, error) {
 			return 0, err
 		}
 	}
-	return version, nil
+	return id, nil
 }
 
-func (m MovieReviewModel) Get(id int64) (*MovieReview, error) {
+// Get returns the review with the given ID, viewerID is the ID of the user making the request
+// and is used to report their own reactions
+func (m MovieReviewModel) Get(id, viewerID int64) (*MovieReview, error) {
 	if id < 1 {
 		return nil, shared.ErrRecordNotFound
 	}
 
+	// Reviews hidden from the viewer are reported as not found
 	query := `
-		 SELECT id, imdb_id, rating, statement_comment, statement_created_at, statement_updated_at,
-                created_at, updated_at, version
-         FROM movie_reviews
-		 WHERE id = $1;`
+		 SELECT ` + movieReviewColumns + `
+         FROM movie_reviews mr
+         INNER JOIN users u ON u.id = mr.user_id
+		 WHERE mr.id = $1
+		 AND ` + visibleToViewer(2)
 
 	var movieReview MovieReview
-	movieReview.Reactions = nil
 
 	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&movieReview.ID,
-		&movieReview.ImdbID,
-		&movieReview.Rating,
-		&movieReview.Statement.Comment,
-		&movieReview.Statement.CreatedAt,
-		&movieReview.Statement.UpdatedAt,
-		&movieReview.CreatedAt,
-		&movieReview.UpdatedAt,
-		&movieReview.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id, viewerID).Scan(movieReview.scanDestinations()...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -148,6 +276,11 @@ func (m MovieReviewModel) Get(id int64) (*MovieReview, error) {
 		}
 	}
 
+	err = m.populateReactions(ctx, viewerID, &movieReview)
+	if err != nil {
+		return nil, err
+	}
+
 	return &movieReview, nil
 }
 
@@ -174,30 +307,37 @@ func (m MovieReviewModel) Update(input *inputs.UpdateMovieReviewInput, id, versi
 
 	args = append(args, id)
 	args = append(args, version)
+	// The CTE lets us join the updated row with its author in a single round trip
 	query := fmt.Sprintf(`
-		UPDATE movie_reviews
-        SET %s
-        WHERE id = $%d AND version = $%d
-        RETURNING id, imdb_id, rating, statement_comment, 
-        statement_created_at, statement_updated_at, created_at, 
-        updated_at, version`, strings.Join(setClauses, ", "), argCount, argCount+1)
+		WITH mr AS (
+			UPDATE movie_reviews
+			SET %s
+			WHERE id = $%d AND version = $%d
+			RETURNING *
+		)
+		SELECT `+movieReviewColumns+`
+		FROM mr
+		INNER JOIN users u ON u.id = mr.user_id`, strings.Join(setClauses, ", "), argCount, argCount+1)
 
 	var movieReview MovieReview
-	movieReview.Reactions = nil
 	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
-		&movieReview.ID,
-		&movieReview.ImdbID,
-		&movieReview.Rating,
-		&movieReview.Statement.Comment,
-		&movieReview.Statement.CreatedAt,
-		&movieReview.Statement.UpdatedAt,
-		&movieReview.CreatedAt,
-		&movieReview.UpdatedAt,
-		&movieReview.Version,
-	)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return &MovieReview{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// The previous state is kept in the edit history, and its rating is needed to update the movie aggregates
+	var previousRating int8
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO movie_review_revisions (movie_review_id, version, rating, statement_comment, statement_updated_at)
+		SELECT id, version, rating, statement_comment, statement_updated_at
+		FROM movie_reviews
+		WHERE id = $1 AND version = $2
+		FOR UPDATE
+		RETURNING rating`, id, version).Scan(&previousRating)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -207,6 +347,38 @@ func (m MovieReviewModel) Update(input *inputs.UpdateMovieReviewInput, id, versi
 		}
 	}
 
+	err = tx.QueryRowContext(ctx, query, args...).Scan(movieReview.scanDestinations()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return &MovieReview{}, shared.ErrEditConflict
+		default:
+			return &MovieReview{}, err
+		}
+	}
+
+	if movieReview.Rating != previousRating {
+		err = adjustStats(ctx, tx, movieReview.ImdbID, movieReview.Author.ID, previousRating, -1)
+		if err != nil {
+			return &MovieReview{}, err
+		}
+		err = adjustStats(ctx, tx, movieReview.ImdbID, movieReview.Author.ID, movieReview.Rating, 1)
+		if err != nil {
+			return &MovieReview{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return &MovieReview{}, err
+	}
+
+	// Only the author can update a review, so they are the one viewing it
+	err = m.populateReactions(ctx, movieReview.Author.ID, &movieReview)
+	if err != nil {
+		return &MovieReview{}, err
+	}
+
 	return &movieReview, nil
 }
 
@@ -217,44 +389,60 @@ func (m MovieReviewModel) Delete(id int64) error {
 
 	query := `
 		DELETE FROM movie_reviews
-		WHERE id = $1;`
+		WHERE id = $1
+		RETURNING imdb_id, rating, user_id;`
 
 	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	rowsAffected, err := result.RowsAffected()
+	var (
+		imdbID   string
+		rating   int8
+		authorID int64
+	)
+	err = tx.QueryRowContext(ctx, query, id).Scan(&imdbID, &rating, &authorID)
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return shared.ErrRecordNotFound
+		default:
+			return err
+		}
 	}
-	if rowsAffected == 0 {
-		return shared.ErrRecordNotFound
+
+	err = adjustStats(ctx, tx, imdbID, authorID, rating, -1)
+	if err != nil {
+		return err
 	}
-	return nil
 
+	return tx.Commit()
 }
 
-func (m MovieReviewModel) GetAll(queryInput *inputs.ListMovieReviewsQueryInput) (reviews []*MovieReview, metadata shared.Metadata, err error) {
-	query := `
-       	SELECT count(*) OVER(), id, imdb_id, rating, statement_comment, statement_created_at, statement_updated_at, created_at, updated_at, version
-        FROM movie_reviews
-       	ORDER BY updated_at DESC
-       	LIMIT $1 OFFSET $2`
+func (m MovieReviewModel) GetAll(queryInput *inputs.ListMovieReviewsQueryInput, viewerID int64) (reviews []*MovieReview, metadata shared.Metadata, err error) {
+	conditions, args := listFilters(queryInput, viewerID)
+	whereClause := buildWhereClause(conditions)
+	searchColumns := listSearchColumns(queryInput)
 
-	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
-	defer cancel()
+	fromClause := `
+        FROM movie_reviews mr
+        INNER JOIN users u ON u.id = mr.user_id
+        ` + whereClause
 
-	// Get the total Count of Records
-	totalRecords := 0
-	err = m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM movie_reviews").Scan(&totalRecords)
+	query := fmt.Sprintf(`
+       	SELECT count(*) OVER(), `+movieReviewColumns+`%s%s
+       	ORDER BY %s
+       	LIMIT $%d OFFSET $%d`, searchColumns, fromClause, queryInput.OrderBy(), len(args)+1, len(args)+2)
 
-	args := []any{queryInput.Limit(), queryInput.Offset()}
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, args...)
+	rows, err := m.DB.QueryContext(ctx, query, append(args, queryInput.Limit(), queryInput.Offset())...)
 	if err != nil {
 		return nil, shared.Metadata{}, err
 	}
@@ -273,23 +461,15 @@ func (m MovieReviewModel) GetAll(queryInput *inputs.ListMovieReviewsQueryInput)
 	}(rows)
 
 	reviews = []*MovieReview{}
-	totalPaginatedRecords := 0
+	totalRecords := 0
 
 	for rows.Next() {
 		var review MovieReview
-		review.Reactions = nil
-
-		err := rows.Scan(
-			&totalPaginatedRecords,
-			&review.ID,
-			&review.ImdbID,
-			&review.Rating,
-			&review.Statement.Comment,
-			&review.Statement.CreatedAt,
-			&review.Statement.UpdatedAt,
-			&review.CreatedAt,
-			&review.UpdatedAt,
-			&review.Version)
+
+		dest := append([]any{&totalRecords}, review.scanDestinations()...)
+		dest = append(dest, review.searchScanDestinations(queryInput)...)
+
+		err := rows.Scan(dest...)
 		if err != nil {
 			return nil, shared.Metadata{}, err
 		}
@@ -299,6 +479,198 @@ func (m MovieReviewModel) GetAll(queryInput *inputs.ListMovieReviewsQueryInput)
 	if err = rows.Err(); err != nil {
 		return nil, shared.Metadata{}, err
 	}
-	metadata = shared.CalculateMetadata(totalPaginatedRecords, totalRecords, queryInput.Page, queryInput.PageSize)
+
+	totalRecords, err = shared.CountRecords(ctx, m.DB, totalRecords, len(reviews), queryInput.PageInput, fromClause, args...)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	err = m.populateReactions(ctx, viewerID, reviews...)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+	metadata = shared.CalculateMetadata(totalRecords, queryInput.Page, queryInput.PageSize)
+	metadata.Sort = queryInput.Sort
+	metadata.Filters = queryInput.Filters()
 	return reviews, metadata, nil
 }
+
+// GetAllByCursor lists reviews using keyset pagination on (updated_at, id): it returns the reviews
+// coming right after the given cursor (the first page when nil) and the cursor of the next page,
+// which is nil when there are no more reviews. Unlike GetAll(), no counting is involved, so the
+// cost of fetching a page doesn't depend on how deep it is
+func (m MovieReviewModel) GetAllByCursor(queryInput *inputs.ListMovieReviewsQueryInput, after *shared.Cursor, viewerID int64) (reviews []*MovieReview, metadata shared.Metadata, next *shared.Cursor, err error) {
+	conditions, args := listFilters(queryInput, viewerID)
+	if after != nil {
+		args = append(args, after.Time, after.ID)
+		conditions = append(conditions, fmt.Sprintf("(mr.updated_at, mr.id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+	whereClause := buildWhereClause(conditions)
+	searchColumns := listSearchColumns(queryInput)
+
+	// We fetch one extra review to know whether there is a next page
+	query := fmt.Sprintf(`
+       	SELECT `+movieReviewColumns+`%s
+        FROM movie_reviews mr
+        INNER JOIN users u ON u.id = mr.user_id
+        %s
+       	ORDER BY mr.updated_at DESC, mr.id DESC
+       	LIMIT $%d`, searchColumns, whereClause, len(args)+1)
+	args = append(args, queryInput.Limit()+1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, shared.Metadata{}, nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	reviews = []*MovieReview{}
+
+	for rows.Next() {
+		var review MovieReview
+
+		dest := append(review.scanDestinations(), review.searchScanDestinations(queryInput)...)
+
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, shared.Metadata{}, nil, err
+		}
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, shared.Metadata{}, nil, err
+	}
+
+	if len(reviews) > queryInput.Limit() {
+		reviews = reviews[:queryInput.Limit()]
+		last := reviews[len(reviews)-1]
+		next = &shared.Cursor{Kind: shared.ReviewsCursor, Time: last.UpdatedAt, ID: last.ID}
+	}
+
+	err = m.populateReactions(ctx, viewerID, reviews...)
+	if err != nil {
+		return nil, shared.Metadata{}, nil, err
+	}
+
+	metadata = shared.CalculateCursorMetadata(queryInput.PageSize, after == nil, next != nil)
+	metadata.Sort = queryInput.Sort
+	metadata.Filters = queryInput.Filters()
+	return reviews, metadata, next, nil
+}
+
+// headlineStartSel and headlineStopSel delimit the matches in the headlines returned by ts_headline
+const (
+	headlineStartSel = "\uE000"
+	headlineStopSel  = "\uE001"
+)
+
+// headlineScanner scans a headline selected by listSearchColumns into safe HTML: the statement, which
+// is raw user input, is escaped and the delimiters of the matches become <mark> tags
+type headlineScanner struct {
+	dst *string
+}
+
+func (s headlineScanner) Scan(src any) error {
+	var headline string
+	switch src := src.(type) {
+	case []byte:
+		headline = string(src)
+	case string:
+		headline = src
+	default:
+		return fmt.Errorf("unexpected headline type %T", src)
+	}
+
+	*s.dst = strings.NewReplacer(headlineStartSel, "<mark>", headlineStopSel, "</mark>").Replace(html.EscapeString(headline))
+	return nil
+}
+
+// listSearchColumns returns the extra columns selected when searching reviews. listFilters() puts
+// the search terms in $1 so that we can rank the results
+func listSearchColumns(queryInput *inputs.ListMovieReviewsQueryInput) string {
+	if queryInput.Query == "" {
+		return ""
+	}
+
+	columns := ", ts_rank(mr.statement_tsv, plainto_tsquery('english', $1)) AS search_rank"
+	if queryInput.Highlight {
+		// The matches are delimited with private use characters, which headlineScanner turns into
+		// <mark> tags once the rest of the text is escaped
+		columns += `, ts_headline('english', mr.statement_comment, plainto_tsquery('english', $1),
+                                  'StartSel="` + headlineStartSel + `", StopSel="` + headlineStopSel + `", MaxFragments=2')`
+	}
+	return columns
+}
+
+// searchScanDestinations returns the destinations of the columns added by listSearchColumns()
+func (review *MovieReview) searchScanDestinations(queryInput *inputs.ListMovieReviewsQueryInput) []any {
+	var dest []any
+	if queryInput.Query != "" {
+		dest = append(dest, &review.SearchRank)
+		if queryInput.Highlight {
+			dest = append(dest, headlineScanner{dst: &review.Headline})
+		}
+	}
+	return dest
+}
+
+// listFilters builds the conditions matching the filters of the query input, along with their
+// positional arguments. The reviews hidden from the viewer are always filtered out
+func listFilters(queryInput *inputs.ListMovieReviewsQueryInput, viewerID int64) ([]string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	// The search terms must come first, GetAll() relies on them being $1
+	if queryInput.Query != "" {
+		addCondition("mr.statement_tsv @@ plainto_tsquery('english', $%d)", queryInput.Query)
+	}
+	if queryInput.ImdbID != "" {
+		addCondition("mr.imdb_id = $%d", queryInput.ImdbID)
+	}
+	if queryInput.UserID != 0 {
+		addCondition("mr.user_id = $%d", queryInput.UserID)
+	}
+	if queryInput.Handle != "" {
+		addCondition("lower(u.handle) = lower($%d)", queryInput.Handle)
+	}
+	if queryInput.MinRating != 0 {
+		addCondition("mr.rating >= $%d", queryInput.MinRating)
+	}
+	if queryInput.MaxRating != 0 {
+		addCondition("mr.rating <= $%d", queryInput.MaxRating)
+	}
+	if queryInput.CreatedAfter != nil {
+		addCondition("mr.created_at >= $%d", *queryInput.CreatedAfter)
+	}
+	if queryInput.CreatedBefore != nil {
+		addCondition("mr.created_at < $%d", *queryInput.CreatedBefore)
+	}
+
+	args = append(args, viewerID)
+	conditions = append(conditions, visibleToViewer(len(args)))
+
+	return conditions, args
+}
+
+// buildWhereClause joins the given conditions into a WHERE clause, which is empty when there are none
+func buildWhereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
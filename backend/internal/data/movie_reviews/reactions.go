@@ -0,0 +1,242 @@
+package movie_reviews
+
+import (
+	"cinepulse.nlt.net/internal/data/movie_reviews/inputs"
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+)
+
+// Reactions lists every reaction a user can leave on a movie review
+var Reactions = []MovieReviewReaction{Agree, Insightful, Funny, ThoughtProvoking, Disagree, WellSaid}
+
+func ValidateReaction(v *validator.Validator, reaction MovieReviewReaction) {
+	v.RequiredString(reaction, "type")
+	v.AddErrorIfNot(validator.PermittedValue(reaction, Reactions...), "type", "must be one of Agree, Insightful, Funny, ThoughtProvoking, Disagree, WellSaid")
+}
+
+// AddReaction records the reaction of a user on a review. Reacting twice the same way is a no-op
+func (m MovieReviewModel) AddReaction(reviewID, userID int64, reaction MovieReviewReaction) error {
+	if reviewID < 1 {
+		return shared.ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	// Users can't react to reviews they aren't allowed to see
+	err := m.checkVisibility(ctx, reviewID, userID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+         INSERT INTO movie_review_reactions (movie_review_id, reaction_type, user_id)
+         VALUES ($1, $2, $3)
+         ON CONFLICT DO NOTHING;`
+
+	_, err = m.DB.ExecContext(ctx, query, reviewID, reaction, userID)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		// The only foreign key which can be violated here is the one on the review as the user is authenticated
+		case errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation":
+			return shared.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckVisibility returns shared.ErrRecordNotFound if the review doesn't exist or is hidden from the viewer
+func (m MovieReviewModel) CheckVisibility(reviewID, viewerID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	return m.checkVisibility(ctx, reviewID, viewerID)
+}
+
+// checkVisibility returns shared.ErrRecordNotFound if the review doesn't exist or is hidden from the viewer
+func (m MovieReviewModel) checkVisibility(ctx context.Context, reviewID, viewerID int64) error {
+	query := `
+         SELECT EXISTS (
+             SELECT 1
+             FROM movie_reviews mr
+             INNER JOIN users u ON u.id = mr.user_id
+             WHERE mr.id = $1
+             AND ` + visibleToViewer(2) + `
+         )`
+
+	var visible bool
+	err := m.DB.QueryRowContext(ctx, query, reviewID, viewerID).Scan(&visible)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RemoveReaction deletes the reaction of a user on a review
+func (m MovieReviewModel) RemoveReaction(reviewID, userID int64, reaction MovieReviewReaction) error {
+	if reviewID < 1 {
+		return shared.ErrRecordNotFound
+	}
+
+	query := `
+         DELETE FROM movie_review_reactions
+         WHERE movie_review_id = $1 AND reaction_type = $2 AND user_id = $3;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, reviewID, reaction, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
+
+// populateReactions fills the reaction counts of all the given reviews, along with the reactions
+// left by the viewer, using a single query
+func (m MovieReviewModel) populateReactions(ctx context.Context, viewerID int64, reviews ...*MovieReview) (err error) {
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	reviewsByID := make(map[int64]*MovieReview, len(reviews))
+	ids := make([]int64, 0, len(reviews))
+	for _, review := range reviews {
+		// Every reaction is reported, even the ones nobody left yet
+		review.Reactions = make(MovieReviewReactionCounts, len(Reactions))
+		for _, reaction := range Reactions {
+			review.Reactions[reaction] = 0
+		}
+		review.ViewerReactions = []MovieReviewReaction{}
+		reviewsByID[review.ID] = review
+		ids = append(ids, review.ID)
+	}
+
+	query := `
+         SELECT movie_review_id, reaction_type, count(*), bool_or(user_id = $2)
+         FROM movie_review_reactions
+         WHERE movie_review_id = ANY($1)
+         GROUP BY movie_review_id, reaction_type
+         ORDER BY movie_review_id, reaction_type;`
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), viewerID)
+	if err != nil {
+		return err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var (
+			reviewID int64
+			reaction MovieReviewReaction
+			count    int64
+			byViewer bool
+		)
+		if err := rows.Scan(&reviewID, &reaction, &count, &byViewer); err != nil {
+			return err
+		}
+
+		review := reviewsByID[reviewID]
+		review.Reactions[reaction] = count
+		if byViewer {
+			review.ViewerReactions = append(review.ViewerReactions, reaction)
+		}
+	}
+
+	return rows.Err()
+}
+
+// MovieReviewReactor is a user who reacted to a review
+type MovieReviewReactor struct {
+	ID       int64               `json:"id"`
+	Handle   string              `json:"handle"`
+	Reaction MovieReviewReaction `json:"reaction"`
+}
+
+// GetReactors lists the users who reacted to a review, optionally filtered by reaction type.
+// Protected users are only listed to themselves and to the users following them
+func (m MovieReviewModel) GetReactors(reviewID, viewerID int64, queryInput *inputs.ListMovieReviewReactionsQueryInput) (reactors []*MovieReviewReactor, metadata shared.Metadata, err error) {
+	if reviewID < 1 {
+		return nil, shared.Metadata{}, shared.ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	err = m.checkVisibility(ctx, reviewID, viewerID)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	fromClause := `
+         FROM movie_review_reactions r
+         INNER JOIN users u ON u.id = r.user_id
+         WHERE r.movie_review_id = $1
+         AND (r.reaction_type::text = $2 OR $2 = '')
+         AND ` + visibleToViewer(3)
+
+	query := `
+         SELECT count(*) OVER(), u.id, u.handle, r.reaction_type` + fromClause + `
+         ORDER BY u.handle, r.reaction_type
+         LIMIT $4 OFFSET $5;`
+
+	args := []any{reviewID, queryInput.Reaction, viewerID}
+
+	rows, err := m.DB.QueryContext(ctx, query, append(args, queryInput.Limit(), queryInput.Offset())...)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	reactors = []*MovieReviewReactor{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var reactor MovieReviewReactor
+		err := rows.Scan(&totalRecords, &reactor.ID, &reactor.Handle, &reactor.Reaction)
+		if err != nil {
+			return nil, shared.Metadata{}, err
+		}
+		reactors = append(reactors, &reactor)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	totalRecords, err = shared.CountRecords(ctx, m.DB, totalRecords, len(reactors), queryInput.PageInput, fromClause, args...)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	metadata = shared.CalculateMetadata(totalRecords, queryInput.Page, queryInput.PageSize)
+	return reactors, metadata, nil
+}
@@ -0,0 +1,320 @@
+package movie_reviews
+
+import (
+	"cinepulse.nlt.net/internal/data/movie_reviews/inputs"
+	"cinepulse.nlt.net/internal/data/shared"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"time"
+)
+
+var ErrInvalidParentReply = errors.New("the parent reply must be a top-level reply of the same review")
+
+// ReviewReply is a reply to a review or, one level deep, to another reply of the same review
+type ReviewReply struct {
+	ID        int64             `json:"id"`
+	ReviewID  int64             `json:"review_id"`
+	ParentID  *int64            `json:"parent_id"` // Nil for the replies made directly to the review
+	Author    MovieReviewAuthor `json:"author"`
+	Comment   string            `json:"comment"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Version   int64             `json:"version"`
+	// The answers to a top-level reply, oldest first. Only set when listing the replies of a review
+	Replies []*ReviewReply `json:"replies,omitempty"`
+}
+
+// reviewReplyColumns are the columns needed to build a ReviewReply, in the order expected by
+// scanDestinations(). Queries using them must alias review_replies as "rr" and users as "u"
+const reviewReplyColumns = `rr.id, rr.movie_review_id, rr.parent_id, rr.comment, rr.created_at, rr.updated_at,
+                rr.version, u.id, u.handle`
+
+func (reply *ReviewReply) scanDestinations() []any {
+	return []any{
+		&reply.ID,
+		&reply.ReviewID,
+		&reply.ParentID,
+		&reply.Comment,
+		&reply.CreatedAt,
+		&reply.UpdatedAt,
+		&reply.Version,
+		&reply.Author.ID,
+		&reply.Author.Handle,
+	}
+}
+
+// InsertReply adds the reply of a user to a review. It returns shared.ErrRecordNotFound if the
+// review doesn't exist or is hidden from the user, and ErrInvalidParentReply if the reply it
+// answers isn't a top-level reply of the same review visible to the user
+func (m MovieReviewModel) InsertReply(reviewID, userID int64, input *inputs.CreateReviewReplyInput) (*ReviewReply, error) {
+	if reviewID < 1 {
+		return nil, shared.ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	// Users can't reply to reviews they aren't allowed to see
+	err := m.checkVisibility(ctx, reviewID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Lock the parent so that it can't be deleted before the answer is inserted. Like the reviews,
+	// the replies of protected users can only be answered by the users allowed to see them
+	if input.ParentID != nil {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `
+         SELECT TRUE
+         FROM review_replies rr
+         INNER JOIN users u ON u.id = rr.user_id
+         WHERE rr.id = $1 AND rr.movie_review_id = $2 AND rr.parent_id IS NULL
+         AND `+visibleToViewer(3)+`
+         FOR SHARE OF rr`, *input.ParentID, reviewID, userID).Scan(&exists)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return nil, ErrInvalidParentReply
+			default:
+				return nil, err
+			}
+		}
+	}
+
+	// The CTE lets us join the inserted row with its author in a single round trip
+	query := `
+		WITH rr AS (
+			INSERT INTO review_replies (movie_review_id, parent_id, user_id, comment)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *
+		)
+		SELECT ` + reviewReplyColumns + `
+		FROM rr
+		INNER JOIN users u ON u.id = rr.user_id`
+
+	var reply ReviewReply
+	err = tx.QueryRowContext(ctx, query, reviewID, input.ParentID, userID, input.Comment).Scan(reply.scanDestinations()...)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		// The review was deleted since its visibility was checked
+		case errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation":
+			return nil, shared.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// GetReplies lists the top-level replies of a review, the oldest first, along with their answers.
+// The replies of protected users follow the same rule as their reviews, and are only listed for
+// the viewers allowed to see them
+func (m MovieReviewModel) GetReplies(reviewID, viewerID int64, queryInput *shared.PageInput) (replies []*ReviewReply, metadata shared.Metadata, err error) {
+	if reviewID < 1 {
+		return nil, shared.Metadata{}, shared.ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	err = m.checkVisibility(ctx, reviewID, viewerID)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	fromClause := `
+         FROM review_replies rr
+         INNER JOIN users u ON u.id = rr.user_id
+         WHERE rr.movie_review_id = $1 AND rr.parent_id IS NULL
+         AND ` + visibleToViewer(2)
+
+	query := `
+         SELECT count(*) OVER(), ` + reviewReplyColumns + fromClause + `
+         ORDER BY rr.created_at, rr.id
+         LIMIT $3 OFFSET $4`
+
+	args := []any{reviewID, viewerID}
+
+	rows, err := m.DB.QueryContext(ctx, query, append(args, queryInput.Limit(), queryInput.Offset())...)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	replies = []*ReviewReply{}
+	totalRecords := 0
+
+	for rows.Next() {
+		reply := ReviewReply{Replies: []*ReviewReply{}}
+		dest := append([]any{&totalRecords}, reply.scanDestinations()...)
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, shared.Metadata{}, err
+		}
+		replies = append(replies, &reply)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	totalRecords, err = shared.CountRecords(ctx, m.DB, totalRecords, len(replies), *queryInput, fromClause, args...)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	err = m.populateAnswers(ctx, viewerID, replies)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	metadata = shared.CalculateMetadata(totalRecords, queryInput.Page, queryInput.PageSize)
+	return replies, metadata, nil
+}
+
+// populateAnswers fills the Replies of the given top-level replies with their answers visible to the viewer
+func (m MovieReviewModel) populateAnswers(ctx context.Context, viewerID int64, parents []*ReviewReply) (err error) {
+	if len(parents) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*ReviewReply, len(parents))
+	ids := make([]int64, 0, len(parents))
+	for _, parent := range parents {
+		byID[parent.ID] = parent
+		ids = append(ids, parent.ID)
+	}
+
+	query := `
+         SELECT ` + reviewReplyColumns + `
+         FROM review_replies rr
+         INNER JOIN users u ON u.id = rr.user_id
+         WHERE rr.parent_id = ANY($1)
+         AND ` + visibleToViewer(2) + `
+         ORDER BY rr.created_at, rr.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), viewerID)
+	if err != nil {
+		return err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var answer ReviewReply
+		err := rows.Scan(answer.scanDestinations()...)
+		if err != nil {
+			return err
+		}
+		parent := byID[*answer.ParentID]
+		parent.Replies = append(parent.Replies, &answer)
+	}
+
+	return rows.Err()
+}
+
+// GetReplyVersionAndAuthorFor returns the current version of a reply along with the IDs of its
+// author and of the review it belongs to. It is used to check ownership before editing or deleting a reply
+func (m MovieReviewModel) GetReplyVersionAndAuthorFor(id int64) (version, authorID, reviewID int64, err error) {
+	if id < 1 {
+		return 0, 0, 0, shared.ErrRecordNotFound
+	}
+
+	query := `
+         SELECT version, user_id, movie_review_id
+         FROM review_replies
+         WHERE id = $1;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, id).Scan(&version, &authorID, &reviewID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, 0, 0, shared.ErrRecordNotFound
+		default:
+			return 0, 0, 0, err
+		}
+	}
+	return version, authorID, reviewID, nil
+}
+
+func (m MovieReviewModel) UpdateReply(input *inputs.UpdateReviewReplyInput, id, version int64) (*ReviewReply, error) {
+	query := `
+		WITH rr AS (
+			UPDATE review_replies
+			SET comment = $1, updated_at = now(), version = version + 1
+			WHERE id = $2 AND version = $3
+			RETURNING *
+		)
+		SELECT ` + reviewReplyColumns + `
+		FROM rr
+		INNER JOIN users u ON u.id = rr.user_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	var reply ReviewReply
+	err := m.DB.QueryRowContext(ctx, query, input.Comment, id, version).Scan(reply.scanDestinations()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, shared.ErrEditConflict
+		default:
+			return nil, err
+		}
+	}
+	return &reply, nil
+}
+
+// DeleteReply deletes a reply, along with its answers when it is a top-level reply
+func (m MovieReviewModel) DeleteReply(id int64) error {
+	if id < 1 {
+		return shared.ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM review_replies
+		WHERE id = $1;`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package movie_reviews
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// MovieReviewRevisionStatement is the comment of a review as it was, UpdatedAt is when that text was written
+type MovieReviewRevisionStatement struct {
+	Comment   string    `json:"comment"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MovieReviewRevision is a previous state of a review, saved when its author edited it
+type MovieReviewRevision struct {
+	Version    int64                        `json:"version"`
+	Rating     int8                         `json:"rating"`
+	Statement  MovieReviewRevisionStatement `json:"statement"`
+	ReplacedAt time.Time                    `json:"replaced_at"` // When the edit replacing this state was made
+}
+
+// GetRevisions lists the previous states of a review, the most recent first
+func (m MovieReviewModel) GetRevisions(reviewID, viewerID int64, queryInput *shared.PageInput) (revisions []*MovieReviewRevision, metadata shared.Metadata, err error) {
+	if reviewID < 1 {
+		return nil, shared.Metadata{}, shared.ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	err = m.checkVisibility(ctx, reviewID, viewerID)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	fromClause := `
+         FROM movie_review_revisions
+         WHERE movie_review_id = $1`
+
+	query := `
+         SELECT count(*) OVER(), version, rating, statement_comment, statement_updated_at, replaced_at` + fromClause + `
+         ORDER BY version DESC
+         LIMIT $2 OFFSET $3`
+
+	rows, err := m.DB.QueryContext(ctx, query, reviewID, queryInput.Limit(), queryInput.Offset())
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	revisions = []*MovieReviewRevision{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var revision MovieReviewRevision
+		err := rows.Scan(
+			&totalRecords,
+			&revision.Version,
+			&revision.Rating,
+			&revision.Statement.Comment,
+			&revision.Statement.UpdatedAt,
+			&revision.ReplacedAt,
+		)
+		if err != nil {
+			return nil, shared.Metadata{}, err
+		}
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	totalRecords, err = shared.CountRecords(ctx, m.DB, totalRecords, len(revisions), *queryInput, fromClause, reviewID)
+	if err != nil {
+		return nil, shared.Metadata{}, err
+	}
+
+	metadata = shared.CalculateMetadata(totalRecords, queryInput.Page, queryInput.PageSize)
+	return revisions, metadata, nil
+}
@@ -0,0 +1,89 @@
+package movie_reviews
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MovieReviewStats holds the rating aggregates of a movie. Only the reviews of public users are
+// aggregated: the ones of protected users are hidden from most viewers, and counting them would
+// disclose their ratings
+type MovieReviewStats struct {
+	ReviewCount   int64   `json:"review_count"`
+	AverageRating float64 `json:"average_rating"` // Rounded to 2 decimals, 0 without reviews
+	// RatingHistogram holds the number of reviews for each rating, from "1" to "5"
+	RatingHistogram map[string]int64 `json:"rating_histogram"`
+}
+
+// adjustStats adds (delta = 1) or removes (delta = -1) a review with the given rating to the
+// aggregates of the movie, unless its author is protected. It must run in the transaction changing
+// the review, and locks the author so that their protection can't change until it is committed
+func adjustStats(ctx context.Context, tx *sql.Tx, imdbID string, authorID int64, rating int8, delta int) error {
+	if rating < 1 || rating > 5 {
+		panic(fmt.Sprintf("invalid rating %d", rating))
+	}
+	ratingColumn := fmt.Sprintf("rating_%d_count", rating)
+
+	query := `
+         INSERT INTO movie_review_stats AS s (imdb_id, review_count, rating_sum, ` + ratingColumn + `)
+         SELECT $1, $2::integer, $2::integer * $3::integer, $2::integer
+         FROM users
+         WHERE id = $4 AND is_protected = FALSE
+         FOR SHARE
+         ON CONFLICT (imdb_id) DO UPDATE
+         SET review_count = s.review_count + EXCLUDED.review_count,
+             rating_sum = s.rating_sum + EXCLUDED.rating_sum,
+             ` + ratingColumn + ` = s.` + ratingColumn + ` + EXCLUDED.` + ratingColumn
+
+	_, err := tx.ExecContext(ctx, query, imdbID, delta, rating, authorID)
+	return err
+}
+
+// GetStats returns the rating aggregates of the movie, or ErrRecordNotFound if it was never reviewed
+// by a public user
+func (m MovieReviewModel) GetStats(imdbID string) (*MovieReviewStats, error) {
+	query := `
+         SELECT review_count, rating_sum, rating_1_count, rating_2_count, rating_3_count, rating_4_count, rating_5_count
+         FROM movie_review_stats
+         WHERE imdb_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeOutDuration)
+	defer cancel()
+
+	var (
+		stats     MovieReviewStats
+		ratingSum int64
+		counts    [5]int64
+	)
+	err := m.DB.QueryRowContext(ctx, query, imdbID).Scan(
+		&stats.ReviewCount,
+		&ratingSum,
+		&counts[0],
+		&counts[1],
+		&counts[2],
+		&counts[3],
+		&counts[4],
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, shared.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if stats.ReviewCount > 0 {
+		stats.AverageRating = math.Round(float64(ratingSum)/float64(stats.ReviewCount)*100) / 100
+	}
+
+	stats.RatingHistogram = make(map[string]int64, len(counts))
+	for i, count := range counts {
+		stats.RatingHistogram[fmt.Sprint(i+1)] = count
+	}
+	return &stats, nil
+}
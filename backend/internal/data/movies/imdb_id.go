@@ -0,0 +1,34 @@
+package movies
+
+import (
+	"cinepulse.nlt.net/internal/validator"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ImdbIDRX matches a canonical IMDb ID such as "tt0111161"
+	ImdbIDRX = regexp.MustCompile(`^tt\d{7,8}$`)
+
+	// imdbIDInputRX matches what users may send as an IMDb ID: the ID itself, whatever the case of
+	// its prefix, or the URL of the title page. The migration 000014 uses the same pattern
+	imdbIDInputRX = regexp.MustCompile(`(?i)^(?:(?:https?://)?(?:www\.|m\.)?imdb\.com/title/)?(tt\d{7,8})(?:[/?#].*)?$`)
+)
+
+// CanonicalImdbID returns the canonical form of an IMDb ID or IMDb title URL. Inputs that can't be
+// understood are only trimmed, ValidateImdbID reports them
+func CanonicalImdbID(input string) string {
+	input = strings.TrimSpace(input)
+
+	matches := imdbIDInputRX.FindStringSubmatch(input)
+	if matches == nil {
+		return input
+	}
+	return strings.ToLower(matches[1])
+}
+
+// ValidateImdbID checks that the (canonicalized) IMDb ID has the "tt" + 7 or 8 digits format
+func ValidateImdbID(v *validator.Validator, imdbID string) {
+	v.RequiredString(imdbID, "imdb_id")
+	v.AddErrorIfNot(validator.Matches(imdbID, ImdbIDRX), "imdb_id", "must be an IMDb ID such as tt0111161, or the URL of an IMDb title")
+}
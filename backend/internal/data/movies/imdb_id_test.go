@@ -0,0 +1,40 @@
+package movies
+
+import (
+	"cinepulse.nlt.net/internal/validator"
+	"testing"
+)
+
+func TestCanonicalImdbID(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		valid bool
+	}{
+		{input: "tt0111161", want: "tt0111161", valid: true},
+		{input: "TT0111161", want: "tt0111161", valid: true},
+		{input: " tt0111161 ", want: "tt0111161", valid: true},
+		{input: "tt10872600", want: "tt10872600", valid: true},
+		{input: "https://www.imdb.com/title/tt0111161/?ref_=x", want: "tt0111161", valid: true},
+		{input: "m.imdb.com/title/tt0111161/", want: "tt0111161", valid: true},
+		{input: "tt123", want: "tt123"},
+		{input: "nm0000001", want: "nm0000001"},
+		{input: "https://www.imdb.com/name/nm0000001/", want: "https://www.imdb.com/name/nm0000001/"},
+		{input: "tt0111161x", want: "tt0111161x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := CanonicalImdbID(tt.input)
+			if got != tt.want {
+				t.Errorf("CanonicalImdbID(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+
+			v := validator.New()
+			ValidateImdbID(v, got)
+			if v.Valid() != tt.valid {
+				t.Errorf("ValidateImdbID(%q) valid = %t, want %t", got, v.Valid(), tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package movies
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrMovieNotFound is returned by the metadata providers when there is no movie with the requested IMDb ID
+var ErrMovieNotFound = errors.New("movie not found")
+
+type Movie struct {
+	ImdbID    string    `json:"imdb_id"`
+	Title     string    `json:"title"`
+	Year      int       `json:"year,omitempty"`
+	PosterURL string    `json:"poster_url,omitempty"`
+	FetchedAt time.Time `json:"-"`
+}
+
+// Summary is the part of a movie embedded in the reviews
+type Summary struct {
+	Title     string `json:"title"`
+	Year      int    `json:"year,omitempty"`
+	PosterURL string `json:"poster_url,omitempty"`
+}
+
+// MetadataProvider looks movies up in an external catalogue such as OMDb
+type MetadataProvider interface {
+	// FetchMovie returns the movie with the given IMDb ID, or ErrMovieNotFound if there is none
+	FetchMovie(ctx context.Context, imdbID string) (*Movie, error)
+}
+
+type MovieModel struct {
+	DB *sql.DB
+}
+
+func (m MovieModel) Get(imdbID string) (*Movie, error) {
+	query := `
+         SELECT imdb_id, title, COALESCE(year, 0), poster_url, fetched_at
+         FROM movies
+         WHERE imdb_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var movie Movie
+	err := m.DB.QueryRowContext(ctx, query, imdbID).Scan(&movie.ImdbID, &movie.Title, &movie.Year, &movie.PosterURL, &movie.FetchedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, shared.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &movie, nil
+}
+
+// Upsert stores a movie fetched from the metadata provider, refreshing it if it was already there
+func (m MovieModel) Upsert(movie *Movie) error {
+	query := `
+         INSERT INTO movies (imdb_id, title, year, poster_url)
+         VALUES ($1, $2, NULLIF($3, 0), $4)
+         ON CONFLICT (imdb_id) DO UPDATE
+         SET title = EXCLUDED.title, year = EXCLUDED.year, poster_url = EXCLUDED.poster_url, fetched_at = now()
+         RETURNING fetched_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, movie.ImdbID, movie.Title, movie.Year, movie.PosterURL).Scan(&movie.FetchedAt)
+}
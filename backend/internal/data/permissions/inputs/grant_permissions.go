@@ -0,0 +1,17 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/data/permissions"
+	"cinepulse.nlt.net/internal/validator"
+)
+
+type GrantPermissionsInput struct {
+	Permissions []string `json:"permissions"`
+}
+
+func ValidateGrantPermissionsInput(v *validator.Validator, input *GrantPermissionsInput) {
+	v.AddErrorIfNot(len(input.Permissions) > 0, "permissions", "must contain at least one permission")
+	for _, code := range input.Permissions {
+		v.AddErrorIfNot(validator.PermittedValue(code, permissions.All...), "permissions", "unknown permission "+code)
+	}
+}
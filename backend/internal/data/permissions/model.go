@@ -0,0 +1,126 @@
+package permissions
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"slices"
+	"time"
+)
+
+const (
+	ReviewsWrite    = "reviews:write"    // Writing, editing and deleting one's own reviews
+	ReviewsModerate = "reviews:moderate" // Deleting the reviews of anyone
+	UsersAdmin      = "users:admin"      // Granting and revoking permissions
+)
+
+// All holds every permission code known to the application
+var All = []string{ReviewsWrite, ReviewsModerate, UsersAdmin}
+
+// Default holds the permissions granted to the users when they activate their account
+var Default = []string{ReviewsWrite}
+
+func ValidatePermission(v *validator.Validator, code string) {
+	v.RequiredString(code, "code")
+	v.AddErrorIfNot(validator.PermittedValue(code, All...), "code", "must be one of reviews:write, reviews:moderate, users:admin")
+}
+
+// Permissions is the list of permission codes of a user
+type Permissions []string
+
+// Include reports whether the given permission code is in the list
+func (p Permissions) Include(code string) bool {
+	return slices.Contains(p, code)
+}
+
+type PermissionModel struct {
+	DB *sql.DB
+}
+
+// GetAllForUser returns the permission codes granted to the user, sorted alphabetically
+func (m PermissionModel) GetAllForUser(userID int64) (permissions Permissions, err error) {
+	query := `
+         SELECT p.code
+         FROM permissions p
+         INNER JOIN users_permissions up ON up.permission_id = p.id
+         WHERE up.user_id = $1
+         ORDER BY p.code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	permissions = Permissions{}
+	for rows.Next() {
+		var code string
+		err := rows.Scan(&code)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, code)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// AddForUser grants the given permissions to the user. Granting a permission twice is a no-op
+func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
+	query := `
+         INSERT INTO users_permissions (user_id, permission_id)
+         SELECT $1, p.id FROM permissions p WHERE p.code = ANY($2)
+         ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "users_permissions" violates foreign key constraint "users_permissions_user_id_fkey"`:
+			return shared.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveForUser revokes the given permission from the user
+func (m PermissionModel) RemoveForUser(userID int64, code string) error {
+	query := `
+         DELETE FROM users_permissions
+         WHERE user_id = $1 AND permission_id = (SELECT id FROM permissions WHERE code = $2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, code)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorKind tells which listing a cursor was issued for, as each listing is keyed differently
+type CursorKind string
+
+const (
+	ReviewsCursor CursorKind = "reviews" // Reviews listing, keyed on (updated_at, id)
+	FeedCursor    CursorKind = "feed"    // Home timeline, keyed on (created_at, id)
+)
+
+// Cursor points at the last record of a page when using keyset pagination on a (timestamp, id)
+// pair, such as (updated_at, id)
+type Cursor struct {
+	Kind CursorKind `json:"k"`
+	Time time.Time  `json:"t"`
+	ID   int64      `json:"i"`
+}
+
+// Encode turns the cursor into an opaque string signed with the given secret, so that clients
+// can't forge or tamper with it
+func (c Cursor) Encode(secret []byte) string {
+	// Marshalling a struct made of a string, a time and an integer can't fail
+	payload, _ := json.Marshal(c)
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	signature := base64.RawURLEncoding.EncodeToString(signCursor(encodedPayload, secret))
+
+	return encodedPayload + "." + signature
+}
+
+// DecodeCursor checks the signature of an opaque cursor produced by Cursor.Encode() and decodes it.
+// Cursors issued for another kind of listing are rejected, they would point at a wrong position
+func DecodeCursor(s string, kind CursorKind, secret []byte) (*Cursor, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(s, ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, signCursor(encodedPayload, secret)) {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	err = json.Unmarshal(payload, &cursor)
+	if err != nil || cursor.ID < 1 || cursor.Kind != kind {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func signCursor(encodedPayload string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encodedPayload))
+	return mac.Sum(nil)
+}
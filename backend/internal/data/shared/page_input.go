@@ -0,0 +1,44 @@
+package shared
+
+import (
+	"cinepulse.nlt.net/internal/validator"
+	"context"
+	"database/sql"
+)
+
+// PageInput holds the "page" and "page_size" query string parameters of the page-based listings
+type PageInput struct {
+	Page     int
+	PageSize int
+}
+
+func (i PageInput) Limit() int {
+	return i.PageSize
+}
+
+func (i PageInput) Offset() int {
+	return (i.Page - 1) * i.PageSize
+}
+
+func ValidatePageInput(v *validator.Validator, input *PageInput) {
+	v.AddErrorIfNot(input.Page > 0, "page", "must be greater than zero")
+	v.AddErrorIfNot(input.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+	v.AddErrorIfNot(input.PageSize > 0, "page_size", "must be greater than zero")
+	v.AddErrorIfNot(input.PageSize <= 100, "page_size", "must be a maximum of 100")
+}
+
+// CountRecords returns the total number of records of a page-based listing, given the count(*) OVER()
+// window selected along with the records of the page. The window count isn't available when the
+// requested page is past the last one, the records of fromClause are then counted separately
+func CountRecords(ctx context.Context, db *sql.DB, windowCount, pageLength int, input PageInput, fromClause string, args ...any) (int, error) {
+	if pageLength > 0 || input.Page <= 1 {
+		return windowCount, nil
+	}
+
+	var totalRecords int
+	err := db.QueryRowContext(ctx, "SELECT count(*)"+fromClause, args...).Scan(&totalRecords)
+	if err != nil {
+		return 0, err
+	}
+	return totalRecords, nil
+}
@@ -1,33 +1,97 @@
 package shared
 
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage int `json:"current_page,omitempty"`
+	PageSize    int `json:"page_size,omitempty"`
+	FirstPage   int `json:"first_page,omitempty"`
+	LastPage    int `json:"last_page,omitempty"`
+	// TotalRecords is nil for cursor-based listings, which don't count the records. Empty
+	// page-based listings still report their count of zero
+	TotalRecords *int `json:"total_records,omitempty"`
+	HasNext      bool `json:"has_next"`
+	HasPrev      bool `json:"has_prev"`
+	// NextCursor is only set when paginating with a cursor and there are more records to fetch
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Sort and Filters echo back how the records were listed, for the endpoints supporting them
+	Sort    string         `json:"sort,omitempty"`
+	Filters map[string]any `json:"filters,omitempty"`
+
+	// Links holds the RFC 8288 links to the other pages, they are sent in the Link header
+	Links []Link `json:"-"`
 }
 
-func CalculateMetadata(totalPaginatedRecords, totalRecords, page, pageSize int) Metadata {
-	if totalRecords == 0 {
-		return Metadata{}
-	}
-	if totalPaginatedRecords == 0 {
-		// We return an empty struct because anyway it will be ignored in the JSON
-		return Metadata{
-			CurrentPage:  page,
-			PageSize:     pageSize,
-			FirstPage:    1,
-			LastPage:     (totalRecords + pageSize - 1) / pageSize,
-			TotalRecords: totalRecords,
-		}
-	}
+type Link struct {
+	URL string
+	Rel string
+}
+
+// CalculateMetadata computes the metadata of a page-based listing. totalRecords must be the number
+// of records matching the listing filters, regardless of the requested page
+func CalculateMetadata(totalRecords, page, pageSize int) Metadata {
+	// An empty listing still has a single (empty) page
+	lastPage := max(1, (totalRecords+pageSize-1)/pageSize)
 
 	return Metadata{
 		CurrentPage:  page,
 		PageSize:     pageSize,
 		FirstPage:    1,
-		LastPage:     (totalPaginatedRecords + pageSize - 1) / pageSize,
-		TotalRecords: totalRecords,
+		LastPage:     lastPage,
+		TotalRecords: &totalRecords,
+		HasNext:      page < lastPage,
+		HasPrev:      page > 1,
+	}
+}
+
+// CalculateCursorMetadata computes the metadata of a cursor-based listing, where records aren't counted
+func CalculateCursorMetadata(pageSize int, isFirstPage, hasNext bool) Metadata {
+	return Metadata{
+		PageSize: pageSize,
+		HasNext:  hasNext,
+		HasPrev:  !isFirstPage,
+	}
+}
+
+// AddLinks computes the links to the first, previous, next and last pages of the listing
+// requested with the given URL. Only the "first" and "next" links are available with cursors
+func (m *Metadata) AddLinks(requestURL *url.URL) {
+	link := func(rel, key, value string) {
+		qs := requestURL.Query()
+		qs.Set(key, value)
+		m.Links = append(m.Links, Link{URL: requestURL.Path + "?" + qs.Encode(), Rel: rel})
+	}
+
+	// Cursor-based listings have no page numbers
+	if m.CurrentPage == 0 {
+		link("first", "cursor", "")
+		if m.HasNext {
+			link("next", "cursor", m.NextCursor)
+		}
+		return
+	}
+
+	link("first", "page", strconv.Itoa(m.FirstPage))
+	// Past the end of the listing, the previous page is the last one rather than another empty page
+	if m.HasPrev {
+		link("prev", "page", strconv.Itoa(min(m.CurrentPage-1, m.LastPage)))
+	}
+	if m.HasNext {
+		link("next", "page", strconv.Itoa(m.CurrentPage+1))
+	}
+	link("last", "page", strconv.Itoa(m.LastPage))
+}
+
+// LinkHeader formats the links as the value of an RFC 8288 Link header
+func (m Metadata) LinkHeader() string {
+	links := make([]string, 0, len(m.Links))
+	for _, link := range m.Links {
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link.URL, link.Rel))
 	}
+	return strings.Join(links, ", ")
 }
@@ -0,0 +1,94 @@
+package tokens
+
+import (
+	"cinepulse.nlt.net/internal/validator"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"time"
+)
+
+type Scope = string
+
+const (
+	ScopeActivation     Scope = "activation"
+	ScopeAuthentication Scope = "authentication"
+	ScopePasswordReset  Scope = "password-reset"
+	ScopeEmailChange    Scope = "email-change"
+	ScopeTwoFactor      Scope = "two-factor" // Password checked, waiting for the second factor
+)
+
+type Token struct {
+	Plaintext string    `json:"token"`
+	Hash      []byte    `json:"-"`
+	UserID    int64     `json:"-"`
+	Expiry    time.Time `json:"expiry"`
+	Scope     Scope     `json:"-"`
+}
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// Hash returns the SHA-256 hash of a plaintext token, which is what we store in the database
+func Hash(tokenPlaintext string) []byte {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+	return hash[:]
+}
+
+// Generate creates a new token for the given user and scope without storing it
+func Generate(userID int64, ttl time.Duration, scope Scope) *Token {
+	token := &Token{
+		// rand.Text() returns 26 random characters from the base32 alphabet (128 bits of entropy)
+		Plaintext: rand.Text(),
+		UserID:    userID,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
+	}
+	token.Hash = Hash(token.Plaintext)
+	return token
+}
+
+func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
+	v.RequiredString(tokenPlaintext, "token")
+	v.AddErrorIfNot(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
+	_, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(tokenPlaintext)
+	v.AddErrorIfNot(err == nil, "token", "must be a valid token")
+}
+
+// New generates a new token for the given user and scope and stores its hash in the database
+func (m TokenModel) New(userID int64, ttl time.Duration, scope Scope) (*Token, error) {
+	token := Generate(userID, ttl, scope)
+
+	err := m.Insert(token)
+	return token, err
+}
+
+func (m TokenModel) Insert(token *Token) error {
+	query := `
+         INSERT INTO tokens (hash, user_id, expiry, scope)
+         VALUES ($1, $2, $3, $4)`
+
+	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeleteAllForUser deletes all the tokens of a given scope for a specific user
+func (m TokenModel) DeleteAllForUser(scope Scope, userID int64) error {
+	query := `
+         DELETE FROM tokens
+         WHERE scope = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, scope, userID)
+	return err
+}
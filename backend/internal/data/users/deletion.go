@@ -0,0 +1,170 @@
+package users
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"time"
+)
+
+// purgeBatchSize is the maximum number of accounts deleted by a single PurgeScheduledDeletions call
+const purgeBatchSize = 100
+
+// DeletedUser is what remains of a purged account, enough to send them a last email
+type DeletedUser struct {
+	ID            int64
+	Email         string
+	ProfileHandle string
+}
+
+// ScheduleDeletion marks the account of the user to be deleted at the given date
+func (m UserModel) ScheduleDeletion(user *User, at time.Time) error {
+	query := `
+         UPDATE users
+         SET deletion_scheduled_at = $1, updated_at = now(), version = version + 1
+         WHERE id = $2 AND version = $3
+         RETURNING deletion_scheduled_at, updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, at, user.ID, user.Version).Scan(&user.DeletionScheduledAt, &user.UpdatedAt, &user.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return shared.ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelDeletion unschedules the deletion of the account of the user, if it was scheduled
+func (m UserModel) CancelDeletion(user *User) error {
+	query := `
+         UPDATE users
+         SET deletion_scheduled_at = NULL, updated_at = now(), version = version + 1
+         WHERE id = $1 AND deletion_scheduled_at IS NOT NULL
+         RETURNING updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, user.ID).Scan(&user.UpdatedAt, &user.Version)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	user.DeletionScheduledAt = nil
+	return nil
+}
+
+// PurgeScheduledDeletions deletes the accounts whose deletion date has passed. Their reviews, reactions,
+// follows and tokens go along thanks to the ON DELETE CASCADE foreign keys
+func (m UserModel) PurgeScheduledDeletions(ctx context.Context) (deleted []*DeletedUser, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	userIDs, err := dueDeletionIDs(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(userIDs) == 0 {
+		return []*DeletedUser{}, nil
+	}
+
+	// The cascade bypasses the movie reviews model, so the movie aggregates are updated here
+	err = adjustReviewStats(ctx, tx, userIDs, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err = deleteUsers(ctx, tx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// dueDeletionIDs locks and returns the IDs of a batch of users whose deletion date has passed
+func dueDeletionIDs(ctx context.Context, tx *sql.Tx) (userIDs []int64, err error) {
+	query := `
+         SELECT id FROM users
+         WHERE deletion_scheduled_at <= now()
+         ORDER BY deletion_scheduled_at
+         LIMIT $1
+         FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, purgeBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	for rows.Next() {
+		var id int64
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// deleteUsers deletes the users with the given IDs and returns what remains of them
+func deleteUsers(ctx context.Context, tx *sql.Tx, userIDs []int64) (deleted []*DeletedUser, err error) {
+	query := `
+         DELETE FROM users
+         WHERE id = ANY($1)
+         RETURNING id, email, handle`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		if cErr := rows.Close(); cErr != nil {
+			err = errors.Join(err, cErr)
+		}
+	}(rows)
+
+	deleted = []*DeletedUser{}
+	for rows.Next() {
+		var user DeletedUser
+		err := rows.Scan(&user.ID, &user.Email, &user.ProfileHandle)
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
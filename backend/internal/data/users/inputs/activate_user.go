@@ -0,0 +1,14 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/data/tokens"
+	"cinepulse.nlt.net/internal/validator"
+)
+
+type ActivateUserInput struct {
+	TokenPlaintext string `json:"token"`
+}
+
+func ValidateActivateUserInput(v *validator.Validator, input *ActivateUserInput) {
+	tokens.ValidateTokenPlaintext(v, input.TokenPlaintext)
+}
@@ -0,0 +1,14 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/data/tokens"
+	"cinepulse.nlt.net/internal/validator"
+)
+
+type ConfirmEmailChangeInput struct {
+	TokenPlaintext string `json:"token"`
+}
+
+func ValidateConfirmEmailChangeInput(v *validator.Validator, input *ConfirmEmailChangeInput) {
+	tokens.ValidateTokenPlaintext(v, input.TokenPlaintext)
+}
@@ -0,0 +1,14 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/data/users/shared"
+	"cinepulse.nlt.net/internal/validator"
+)
+
+type DeleteUserInput struct {
+	Password string `json:"password"`
+}
+
+func ValidateDeleteUserInput(v *validator.Validator, input *DeleteUserInput) {
+	shared.ValidatePasswordPlaintext(v, input.Password)
+}
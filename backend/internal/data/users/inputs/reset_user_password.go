@@ -0,0 +1,25 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/data/tokens"
+	"cinepulse.nlt.net/internal/data/users/shared"
+	"cinepulse.nlt.net/internal/validator"
+)
+
+type RequestPasswordResetInput struct {
+	Email string `json:"email"`
+}
+
+func ValidateRequestPasswordResetInput(v *validator.Validator, input *RequestPasswordResetInput) {
+	shared.ValidateEmail(v, input.Email)
+}
+
+type ResetUserPasswordInput struct {
+	Password       string `json:"password"`
+	TokenPlaintext string `json:"token"`
+}
+
+func ValidateResetUserPasswordInput(v *validator.Validator, input *ResetUserPasswordInput) {
+	shared.ValidatePasswordPlaintext(v, input.Password)
+	tokens.ValidateTokenPlaintext(v, input.TokenPlaintext)
+}
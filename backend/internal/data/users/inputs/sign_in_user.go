@@ -0,0 +1,16 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/data/users/shared"
+	"cinepulse.nlt.net/internal/validator"
+)
+
+type SignInUserInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func ValidateSignInUserInput(v *validator.Validator, input *SignInUserInput) {
+	shared.ValidateEmail(v, input.Email)
+	shared.ValidatePasswordPlaintext(v, input.Password)
+}
@@ -0,0 +1,45 @@
+package inputs
+
+import (
+	"cinepulse.nlt.net/internal/data/tokens"
+	"cinepulse.nlt.net/internal/data/users/shared"
+	"cinepulse.nlt.net/internal/validator"
+)
+
+type EnrollTwoFactorInput struct {
+	Password string `json:"password"`
+}
+
+func ValidateEnrollTwoFactorInput(v *validator.Validator, input *EnrollTwoFactorInput) {
+	shared.ValidatePasswordPlaintext(v, input.Password)
+}
+
+type VerifyTwoFactorInput struct {
+	Code string `json:"code"`
+}
+
+func ValidateVerifyTwoFactorInput(v *validator.Validator, input *VerifyTwoFactorInput) {
+	shared.ValidateTOTPCode(v, input.Code)
+}
+
+// TwoFactorSignInInput completes a sign-in with either a TOTP code or a recovery code
+type TwoFactorSignInInput struct {
+	TokenPlaintext string `json:"token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func ValidateTwoFactorSignInInput(v *validator.Validator, input *TwoFactorSignInInput) {
+	tokens.ValidateTokenPlaintext(v, input.TokenPlaintext)
+
+	switch {
+	case input.Code != "" && input.RecoveryCode != "":
+		v.AddError("code", "must not be used along with recovery_code")
+	case input.Code != "":
+		shared.ValidateTOTPCode(v, input.Code)
+	case input.RecoveryCode != "":
+		v.AddErrorIfNot(len(shared.NormalizeRecoveryCode(input.RecoveryCode)) == 10, "recovery_code", "must be a valid recovery code")
+	default:
+		v.AddError("code", "either code or recovery_code must be provided")
+	}
+}
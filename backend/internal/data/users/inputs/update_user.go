@@ -1,9 +1,13 @@
 package inputs
 
-import "cinepulse.nlt.net/internal/validator"
+import (
+	"cinepulse.nlt.net/internal/data/users/shared"
+	"cinepulse.nlt.net/internal/validator"
+	"unicode/utf8"
+)
 
 type UpdateUserInput struct {
-	Email         *string `json:"email"`
+	Email         *string `json:"email"` // Only starts an email change, which has to be confirmed
 	ProfileHandle *string `json:"profile_handle"`
 	Location      *string `json:"location"`
 	IsProtected   *bool   `json:"is_protected"` // Does not cause version change
@@ -13,4 +17,17 @@ func ValidateUpdateUserInput(v *validator.Validator, input *UpdateUserInput) {
 	if input.Email == nil && input.ProfileHandle == nil && input.Location == nil && input.IsProtected == nil {
 		v.AddError("all", "at least one of email, profile_handle, location, is_protected must be set")
 	}
+
+	if input.Email != nil {
+		shared.ValidateEmail(v, *input.Email)
+	}
+
+	if input.ProfileHandle != nil {
+		shared.ValidateProfileHandle(v, *input.ProfileHandle)
+	}
+
+	if input.Location != nil {
+		v.RequiredString(*input.Location, "location")
+		v.AddErrorIfNot(utf8.RuneCountInString(*input.Location) <= 100, "location", "must not be more than 100 characters")
+	}
 }
@@ -2,6 +2,7 @@ package users
 
 import (
 	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/data/tokens"
 	"cinepulse.nlt.net/internal/data/users/inputs"
 	usersShared "cinepulse.nlt.net/internal/data/users/shared"
 	"cinepulse.nlt.net/internal/validator"
@@ -14,17 +15,39 @@ import (
 )
 
 type User struct {
-	ID            int64                `json:"id"`
-	Email         string               `json:"email"`
-	Password      usersShared.Password `json:"-"`
-	ProfileHandle string               `json:"profile_handle"`
-	Location      string               `json:"location"`
-	DateOfBirth   time.Time            `json:"date_of_birth"`
-	IsProtected   bool                 `json:"is_protected"`
-	IsActivated   bool                 `json:"is_activated"`
-	CreatedAt     time.Time            `json:"created_at"`
-	UpdatedAt     time.Time            `json:"updated_at"`
-	Version       int                  `json:"version"`
+	ID                  int64                `json:"id"`
+	Email               string               `json:"email"`
+	Password            usersShared.Password `json:"-"`
+	ProfileHandle       string               `json:"profile_handle"`
+	Location            string               `json:"location"`
+	DateOfBirth         time.Time            `json:"date_of_birth"`
+	IsProtected         bool                 `json:"is_protected"`
+	IsActivated         bool                 `json:"is_activated"`
+	TOTPEnabled         bool                 `json:"totp_enabled"`
+	DeletionScheduledAt *time.Time           `json:"deletion_scheduled_at,omitempty"` // Cancelled by signing in again
+	CreatedAt           time.Time            `json:"created_at"`
+	UpdatedAt           time.Time            `json:"updated_at"`
+	Version             int                  `json:"version"`
+}
+
+// PublicProfile is the projection of a user that anyone can see. It must never expose private
+// information such as the email address or the date of birth
+type PublicProfile struct {
+	ID             int64     `json:"id"`
+	ProfileHandle  string    `json:"profile_handle"`
+	Location       string    `json:"location"`
+	IsProtected    bool      `json:"is_protected"`
+	CreatedAt      time.Time `json:"created_at"`
+	FollowersCount int64     `json:"followers_count"`
+	FollowingCount int64     `json:"following_count"`
+}
+
+// AnonymousUser represents a client making a request without any authentication token
+var AnonymousUser = &User{}
+
+// IsAnonymous returns true if the user is the AnonymousUser instance
+func (u *User) IsAnonymous() bool {
+	return u == AnonymousUser
 }
 
 type CreatedUserOutput struct {
@@ -133,7 +156,7 @@ func (m UserModel) GetByEmailOrId(property UserSearchByProperty, value any) (*Us
 	}
 
 	query := fmt.Sprintf(`
-         SELECT id, email, password_hash, handle, location, date_of_birth, is_protected, is_activated, created_at, updated_at, version
+         SELECT id, email, password_hash, handle, location, date_of_birth, is_protected, is_activated, totp_enabled, deletion_scheduled_at, created_at, updated_at, version
          FROM users
          WHERE %s = $1`, propertyQueryString)
 
@@ -151,6 +174,8 @@ func (m UserModel) GetByEmailOrId(property UserSearchByProperty, value any) (*Us
 		&user.DateOfBirth,
 		&user.IsProtected,
 		&user.IsActivated,
+		&user.TOTPEnabled,
+		&user.DeletionScheduledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.Version,
@@ -166,19 +191,37 @@ func (m UserModel) GetByEmailOrId(property UserSearchByProperty, value any) (*Us
 	return &user, nil
 }
 
-func (m UserModel) Update(input *inputs.UpdateUserInput, version int, userId int64) error {
+// Update applies the profile changes of the input. The email address is left untouched as it can only
+// be changed through RequestEmailChange and ConfirmEmailChange
+func (m UserModel) Update(input *inputs.UpdateUserInput, version int, userId int64) (*User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	user, err := updateProfile(ctx, tx, input, version, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// updateProfile runs the update of Update within the given transaction
+func updateProfile(ctx context.Context, tx *sql.Tx, input *inputs.UpdateUserInput, version int, userId int64) (*User, error) {
 	var fields []string
 	var args []any
 	argPos := 1
 	incrementVersion := false
 
-	if input.Email != nil {
-		fields = append(fields, fmt.Sprintf("email = $%d", argPos))
-		args = append(args, *input.Email)
-		argPos++
-		incrementVersion = true
-	}
-
 	if input.ProfileHandle != nil {
 		fields = append(fields, fmt.Sprintf("handle = $%d", argPos))
 		args = append(args, *input.ProfileHandle)
@@ -206,34 +249,152 @@ func (m UserModel) Update(input *inputs.UpdateUserInput, version int, userId int
 
 	query := fmt.Sprintf(`
 							 UPDATE users SET %s WHERE id = $%d AND version = $%d
-							 RETURNING id, email, handle, location, date_of_birth, is_protected, is_activated, created_at, updated_at, version
+							 RETURNING id, email, handle, location, date_of_birth, is_protected, is_activated, totp_enabled, deletion_scheduled_at, created_at, updated_at, version
 							 `, strings.Join(fields, ", "), argPos, argPos+1)
 	args = append(args, userId, version)
 
+	// The movie aggregates only count the reviews of public users, so they follow the protection
+	// changes: the reviews are removed while the user is still public, and added once they are
+	var wasProtected bool
+	if input.IsProtected != nil {
+		err := tx.QueryRowContext(ctx, "SELECT is_protected FROM users WHERE id = $1 FOR UPDATE", userId).Scan(&wasProtected)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return nil, shared.ErrEditConflict
+			default:
+				return nil, err
+			}
+		}
+
+		if *input.IsProtected && !wasProtected {
+			err = adjustReviewStats(ctx, tx, []int64{userId}, -1)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var user User
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.Email,
+		&user.ProfileHandle,
+		&user.Location,
+		&user.DateOfBirth,
+		&user.IsProtected,
+		&user.IsActivated,
+		&user.TOTPEnabled,
+		&user.DeletionScheduledAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_handle_key"`:
+			return nil, ErrDuplicateProfileHandle
+
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, shared.ErrEditConflict
+		default:
+			return nil, err
+		}
+	}
+
+	// A public profile can be followed by anyone, so the pending follow requests are approved
+	if !user.IsProtected {
+		err = approveFollowRequests(ctx, tx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if wasProtected && !user.IsProtected {
+		err = adjustReviewStats(ctx, tx, []int64{user.ID}, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// approveFollowRequests turns all the pending follow requests targeting the user into follows
+func approveFollowRequests(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `
+         WITH approved AS (
+             DELETE FROM follow_requests
+             WHERE target_id = $1
+             RETURNING requester_id, target_id
+         )
+         INSERT INTO user_followings (follower_id, following_id)
+         SELECT requester_id, target_id FROM approved
+         ON CONFLICT DO NOTHING`
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
+
+// GetForToken returns the user associated with a non-expired token of the given scope
+func (m UserModel) GetForToken(tokenScope tokens.Scope, tokenPlaintext string) (*User, error) {
+	query := `
+         SELECT users.id, users.email, users.password_hash, users.handle, users.location, users.date_of_birth,
+                users.is_protected, users.is_activated, users.totp_enabled, users.deletion_scheduled_at, users.created_at, users.updated_at, users.version
+         FROM users
+         INNER JOIN tokens
+         ON users.id = tokens.user_id
+         WHERE tokens.hash = $1
+         AND tokens.scope = $2
+         AND tokens.expiry > $3`
+
+	args := []any{tokens.Hash(tokenPlaintext), tokenScope, time.Now()}
+
+	var user User
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	var user User
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
 		&user.Email,
+		&user.Password.Hash,
 		&user.ProfileHandle,
 		&user.Location,
 		&user.DateOfBirth,
 		&user.IsProtected,
 		&user.IsActivated,
+		&user.TOTPEnabled,
+		&user.DeletionScheduledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.Version,
 	)
 	if err != nil {
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
-			return ErrDuplicateEmail
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, shared.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
 
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_handle_key"`:
-			return ErrDuplicateProfileHandle
+// Activate flips the is_activated flag of the user and bumps its version
+func (m UserModel) Activate(user *User) error {
+	query := `
+         UPDATE users
+         SET is_activated = true, updated_at = now(), version = version + 1
+         WHERE id = $1 AND version = $2
+         RETURNING is_activated, updated_at, version`
 
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, user.ID, user.Version).Scan(&user.IsActivated, &user.UpdatedAt, &user.Version)
+	if err != nil {
+		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return shared.ErrEditConflict
 		default:
@@ -242,3 +403,169 @@ func (m UserModel) Update(input *inputs.UpdateUserInput, version int, userId int
 	}
 	return nil
 }
+
+// GetPublicProfileByHandle returns the public projection of the user with the given handle
+func (m UserModel) GetPublicProfileByHandle(handle string) (*PublicProfile, error) {
+	query := `
+         SELECT u.id, u.handle, COALESCE(u.location, ''), u.is_protected, u.created_at,
+                (SELECT count(*) FROM user_followings f WHERE f.following_id = u.id),
+                (SELECT count(*) FROM user_followings f WHERE f.follower_id = u.id)
+         FROM users u
+         WHERE u.handle = $1`
+
+	var profile PublicProfile
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, handle).Scan(
+		&profile.ID,
+		&profile.ProfileHandle,
+		&profile.Location,
+		&profile.IsProtected,
+		&profile.CreatedAt,
+		&profile.FollowersCount,
+		&profile.FollowingCount,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, shared.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &profile, nil
+}
+
+// UpdatePassword stores the new password hash of the user and bumps its version
+func (m UserModel) UpdatePassword(user *User) error {
+	query := `
+         UPDATE users
+         SET password_hash = $1, updated_at = now(), version = version + 1
+         WHERE id = $2 AND version = $3
+         RETURNING updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, user.Password.Hash, user.ID, user.Version).Scan(&user.UpdatedAt, &user.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return shared.ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestEmailChange records newEmail as the pending email address of the user, replacing any
+// previous pending change, and binds it to the token that has to be presented to confirm it. The
+// previous email change tokens are revoked so that they can't confirm the new address. When input
+// holds profile changes, they are applied first in the same transaction: a failed update doesn't
+// leave a pending change behind. It fails with ErrDuplicateEmail if the address already belongs to someone
+func (m UserModel) RequestEmailChange(input *inputs.UpdateUserInput, user *User, newEmail string, token *tokens.Token) (*User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if input.ProfileHandle != nil || input.Location != nil || input.IsProtected != nil {
+		user, err = updateProfile(ctx, tx, input, user.Version, user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Deleting the previous tokens also deletes the pending change bound to them
+	_, err = tx.ExecContext(ctx, "DELETE FROM tokens WHERE scope = $1 AND user_id = $2", tokens.ScopeEmailChange, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+         INSERT INTO tokens (hash, user_id, expiry, scope)
+         VALUES ($1, $2, $3, $4)`, token.Hash, user.ID, token.Expiry, token.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+         INSERT INTO email_changes (user_id, new_email, token_hash)
+         SELECT $1, $2, $3
+         WHERE NOT EXISTS (SELECT 1 FROM users WHERE email = $2)
+         ON CONFLICT (user_id) DO UPDATE
+         SET new_email = EXCLUDED.new_email, token_hash = EXCLUDED.token_hash, created_at = now()`
+
+	result, err := tx.ExecContext(ctx, query, user.ID, newEmail, token.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrDuplicateEmail
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ConfirmEmailChange replaces the email address of the user with the pending one bound to the given
+// token. The activation status is kept as is, the ownership of the new address being proven by the token
+func (m UserModel) ConfirmEmailChange(user *User, tokenPlaintext string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var newEmail string
+	err = tx.QueryRowContext(ctx, `
+         DELETE FROM email_changes
+         WHERE user_id = $1 AND token_hash = $2
+         RETURNING new_email`, user.ID, tokens.Hash(tokenPlaintext)).Scan(&newEmail)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return shared.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query := `
+         UPDATE users
+         SET email = $1, updated_at = now(), version = version + 1
+         WHERE id = $2 AND version = $3
+         RETURNING email, updated_at, version`
+
+	err = tx.QueryRowContext(ctx, query, newEmail, user.ID, user.Version).Scan(&user.Email, &user.UpdatedAt, &user.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+			return ErrDuplicateEmail
+		case errors.Is(err, sql.ErrNoRows):
+			return shared.ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,39 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+)
+
+// adjustReviewStats adds (sign = 1) or removes (sign = -1) all the reviews of the given users to the
+// movie aggregates kept by the movie reviews model. Like the model, only the reviews of the users who
+// are currently public are taken into account, the users must be locked by the transaction. The stats
+// rows are upserted in imdb_id order, so that concurrent adjustments lock them in the same order
+func adjustReviewStats(ctx context.Context, tx *sql.Tx, userIDs []int64, sign int) error {
+	query := `
+         INSERT INTO movie_review_stats AS s (imdb_id, review_count, rating_sum,
+                                              rating_1_count, rating_2_count, rating_3_count, rating_4_count, rating_5_count)
+         SELECT mr.imdb_id, $2 * count(*), $2 * sum(mr.rating),
+                $2 * count(*) FILTER (WHERE mr.rating = 1),
+                $2 * count(*) FILTER (WHERE mr.rating = 2),
+                $2 * count(*) FILTER (WHERE mr.rating = 3),
+                $2 * count(*) FILTER (WHERE mr.rating = 4),
+                $2 * count(*) FILTER (WHERE mr.rating = 5)
+         FROM movie_reviews mr
+         INNER JOIN users u ON u.id = mr.user_id
+         WHERE mr.user_id = ANY($1) AND u.is_protected = FALSE
+         GROUP BY mr.imdb_id
+         ORDER BY mr.imdb_id
+         ON CONFLICT (imdb_id) DO UPDATE
+         SET review_count = s.review_count + EXCLUDED.review_count,
+             rating_sum = s.rating_sum + EXCLUDED.rating_sum,
+             rating_1_count = s.rating_1_count + EXCLUDED.rating_1_count,
+             rating_2_count = s.rating_2_count + EXCLUDED.rating_2_count,
+             rating_3_count = s.rating_3_count + EXCLUDED.rating_3_count,
+             rating_4_count = s.rating_4_count + EXCLUDED.rating_4_count,
+             rating_5_count = s.rating_5_count + EXCLUDED.rating_5_count`
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(userIDs), sign)
+	return err
+}
@@ -0,0 +1,103 @@
+package shared
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238). They are the defaults of the authenticator apps, some of which
+// ignore the parameters of the otpauth:// URI
+const (
+	TOTPIssuer = "CinePulse"
+	TOTPDigits = 6
+	totpModulo = 1_000_000 // 10^TOTPDigits
+	TOTPPeriod = 30 * time.Second
+	// TOTPSkew is the number of periods accepted before and after the current one, to allow for clock drift
+	TOTPSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random 160 bits secret, the size recommended for HMAC-SHA1
+func GenerateTOTPSecret() []byte {
+	secret := make([]byte, 20)
+	_, _ = rand.Read(secret) // rand.Read never returns an error
+	return secret
+}
+
+// EncodeTOTPSecret returns the base32 form of the secret, which users can type in their authenticator app
+func EncodeTOTPSecret(secret []byte) string {
+	return totpEncoding.EncodeToString(secret)
+}
+
+// TOTPKeyURI returns the otpauth:// URI of the secret, usually displayed as a QR code
+func TOTPKeyURI(secret []byte, accountName string) string {
+	label := url.PathEscape(TOTPIssuer + ":" + accountName)
+
+	qs := url.Values{}
+	qs.Set("secret", EncodeTOTPSecret(secret))
+	qs.Set("issuer", TOTPIssuer)
+	qs.Set("algorithm", "SHA1")
+	qs.Set("digits", fmt.Sprint(TOTPDigits))
+	qs.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+
+	return "otpauth://totp/" + label + "?" + qs.Encode()
+}
+
+// TOTPStep returns the time step the given time belongs to
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// TOTPCode computes the code of the given time step (RFC 4226 HOTP with the step as counter)
+func TOTPCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", TOTPDigits, value%totpModulo)
+}
+
+// MatchTOTPCode checks the code against the steps around the given time. It returns the matching
+// step, which callers should record to refuse the same code being used twice
+func MatchTOTPCode(secret []byte, code string, t time.Time) (int64, bool) {
+	current := TOTPStep(t)
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(TOTPCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns n random recovery codes formatted as "XXXXX-XXXXX"
+func GenerateRecoveryCodes(n int) []string {
+	codes := make([]string, n)
+	for i := range codes {
+		text := rand.Text()
+		codes[i] = text[:5] + "-" + text[5:10]
+	}
+	return codes
+}
+
+// NormalizeRecoveryCode removes the formatting of a recovery code typed by a user
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return strings.ToUpper(code)
+}
@@ -2,6 +2,7 @@ package shared
 
 import (
 	"cinepulse.nlt.net/internal/validator"
+	"regexp"
 	"time"
 	"unicode/utf8"
 )
@@ -17,6 +18,13 @@ func ValidateProfileHandle(v *validator.Validator, handle string) {
 	v.AddErrorIfNot(utf8.RuneCountInString(handle) <= 30, "handle", "must not be more than 30 characters")
 }
 
+var totpCodeRX = regexp.MustCompile(`^[0-9]{6}$`)
+
+func ValidateTOTPCode(v *validator.Validator, code string) {
+	v.RequiredString(code, "code")
+	v.AddErrorIfNot(validator.Matches(code, totpCodeRX), "code", "must be a 6 digits code")
+}
+
 func IsAtLeast13YearsOld(birthDate time.Time) bool {
 	limit := time.Now().AddDate(-13, 0, 0)
 	return birthDate.Before(limit) || birthDate.Equal(limit)
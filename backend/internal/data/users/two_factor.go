@@ -0,0 +1,165 @@
+package users
+
+import (
+	"cinepulse.nlt.net/internal/data/shared"
+	"cinepulse.nlt.net/internal/data/tokens"
+	usersShared "cinepulse.nlt.net/internal/data/users/shared"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication enrollment wasn't started")
+)
+
+// SetTOTPSecret stores the secret of a user starting the two-factor authentication enrollment. The
+// secret isn't used to sign in until EnableTOTP is called with a code proving the user has it
+func (m UserModel) SetTOTPSecret(userID int64, secret []byte) error {
+	query := `
+         UPDATE users
+         SET totp_secret = $1, totp_last_step = NULL
+         WHERE id = $2 AND totp_enabled = FALSE`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, secret, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTwoFactorAlreadyEnabled
+	}
+	return nil
+}
+
+// GetTOTPSecret returns the TOTP secret of the user, or ErrTwoFactorNotEnrolled if there is none
+func (m UserModel) GetTOTPSecret(userID int64) ([]byte, error) {
+	query := `
+         SELECT totp_secret
+         FROM users
+         WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var secret []byte
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, shared.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if secret == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	return secret, nil
+}
+
+// EnableTOTP turns two-factor authentication on once the user proved to own the secret with a code of
+// the given step, and replaces the recovery codes of the user
+func (m UserModel) EnableTOTP(user *User, step int64, recoveryCodes []string) error {
+	query := `
+         UPDATE users
+         SET totp_enabled = TRUE, totp_last_step = $1, updated_at = now(), version = version + 1
+         WHERE id = $2 AND version = $3 AND totp_secret IS NOT NULL AND totp_enabled = FALSE
+         RETURNING totp_enabled, updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	err = tx.QueryRowContext(ctx, query, step, user.ID, user.Version).Scan(&user.TOTPEnabled, &user.UpdatedAt, &user.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return shared.ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, code := range recoveryCodes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (hash, user_id) VALUES ($1, $2)",
+			hashRecoveryCode(code), user.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RecordTOTPStep marks the step of a TOTP code as used. It returns false if that step or a later one
+// was already used, meaning that the code is being replayed
+func (m UserModel) RecordTOTPStep(userID int64, step int64) (bool, error) {
+	query := `
+         UPDATE users
+         SET totp_last_step = $1
+         WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// UseRecoveryCode consumes one of the recovery codes of the user. It returns ErrRecordNotFound if the
+// code doesn't exist or was already used
+func (m UserModel) UseRecoveryCode(userID int64, code string) error {
+	query := `
+         DELETE FROM recovery_codes
+         WHERE hash = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, hashRecoveryCode(code), userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
+
+// hashRecoveryCode hashes a recovery code like the tokens, regardless of how the user formatted it
+func hashRecoveryCode(code string) []byte {
+	return tokens.Hash(usersShared.NormalizeRecoveryCode(code))
+}
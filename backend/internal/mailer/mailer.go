@@ -12,7 +12,12 @@ import (
 var templateFS embed.FS
 
 var (
-	UserWelcomeTemplate = "user_welcome"
+	UserWelcomeTemplate       = "user_welcome"
+	PasswordResetTemplate     = "token_password_reset"
+	EmailChangeTemplate       = "token_email_change"
+	EmailChangeNoticeTemplate = "email_change_notice"
+	DeletionScheduledTemplate = "account_deletion_scheduled"
+	AccountDeletedTemplate    = "account_deleted"
 )
 
 type Mailer struct {
@@ -1,7 +1,40 @@
 package types
 
 type UserWelcomeTemplateData struct {
-	ProfileHandle  string `json:"profileHandle"`
-	CurrentYear    int    `json:"currentYear"`
-	ActivationLink string `json:"activationLink"`
+	ProfileHandle   string `json:"profileHandle"`
+	CurrentYear     int    `json:"currentYear"`
+	ActivationLink  string `json:"activationLink"`
+	ActivationToken string `json:"activationToken"`
+}
+
+type PasswordResetTemplateData struct {
+	ProfileHandle      string `json:"profileHandle"`
+	CurrentYear        int    `json:"currentYear"`
+	PasswordResetLink  string `json:"passwordResetLink"`
+	PasswordResetToken string `json:"passwordResetToken"`
+}
+
+type EmailChangeTemplateData struct {
+	ProfileHandle    string `json:"profileHandle"`
+	CurrentYear      int    `json:"currentYear"`
+	NewEmail         string `json:"newEmail"`
+	EmailChangeLink  string `json:"emailChangeLink"`
+	EmailChangeToken string `json:"emailChangeToken"`
+}
+
+type EmailChangeNoticeTemplateData struct {
+	ProfileHandle string `json:"profileHandle"`
+	CurrentYear   int    `json:"currentYear"`
+	NewEmail      string `json:"newEmail"`
+}
+
+type DeletionScheduledTemplateData struct {
+	ProfileHandle string `json:"profileHandle"`
+	CurrentYear   int    `json:"currentYear"`
+	DeletionDate  string `json:"deletionDate"`
+}
+
+type AccountDeletedTemplateData struct {
+	ProfileHandle string `json:"profileHandle"`
+	CurrentYear   int    `json:"currentYear"`
 }
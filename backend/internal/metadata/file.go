@@ -0,0 +1,42 @@
+package metadata
+
+import (
+	"cinepulse.nlt.net/internal/data/movies"
+	"context"
+	"encoding/json"
+	"os"
+)
+
+// File serves the movies listed in a JSON file. It is meant for development and tests, where
+// calling the real provider isn't wanted
+type File struct {
+	movies map[string]movies.Movie
+}
+
+// NewFile loads the movies of a JSON file holding an array of movies.Movie
+func NewFile(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []movies.Movie
+	err = json.Unmarshal(content, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &File{movies: make(map[string]movies.Movie, len(list))}
+	for _, movie := range list {
+		p.movies[movie.ImdbID] = movie
+	}
+	return p, nil
+}
+
+func (p *File) FetchMovie(_ context.Context, imdbID string) (*movies.Movie, error) {
+	movie, ok := p.movies[imdbID]
+	if !ok {
+		return nil, movies.ErrMovieNotFound
+	}
+	return &movie, nil
+}
@@ -0,0 +1,35 @@
+package metadata
+
+import (
+	"cinepulse.nlt.net/internal/data/movies"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFileFetchMovie(t *testing.T) {
+	p, err := NewFile("testdata/movies.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	movie, err := p.FetchMovie(context.Background(), "tt15398776")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if movie.Title != "Oppenheimer" || movie.Year != 2023 {
+		t.Errorf("got %+v, want Oppenheimer (2023)", *movie)
+	}
+
+	_, err = p.FetchMovie(context.Background(), "tt0000001")
+	if !errors.Is(err, movies.ErrMovieNotFound) {
+		t.Errorf("err = %v, want ErrMovieNotFound", err)
+	}
+}
+
+func TestNewFileMissing(t *testing.T) {
+	_, err := NewFile("testdata/missing.json")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"cinepulse.nlt.net/internal/data/movies"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const omdbBaseURL = "https://www.omdbapi.com/"
+
+// OMDb fetches the movies from the OMDb API (https://www.omdbapi.com)
+type OMDb struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func NewOMDb(apiKey string) *OMDb {
+	return &OMDb{
+		apiKey:  apiKey,
+		baseURL: omdbBaseURL,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type omdbResponse struct {
+	Response string `json:"Response"` // "True" or "False"
+	Error    string `json:"Error"`
+	ImdbID   string `json:"imdbID"`
+	Title    string `json:"Title"`
+	Year     string `json:"Year"`
+	Poster   string `json:"Poster"`
+}
+
+func (p *OMDb) FetchMovie(ctx context.Context, imdbID string) (*movies.Movie, error) {
+	qs := url.Values{}
+	qs.Set("i", imdbID)
+	qs.Set("apikey", p.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"?"+qs.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("omdb: unexpected status %s", res.Status)
+	}
+
+	var body omdbResponse
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	// OMDb reports errors, including unknown movies, with a 200 status and Response set to "False"
+	if body.Response != "True" {
+		switch body.Error {
+		case "Incorrect IMDb ID.", "Movie not found!", "Error getting data.":
+			return nil, movies.ErrMovieNotFound
+		default:
+			return nil, errors.New("omdb: " + body.Error)
+		}
+	}
+
+	movie := &movies.Movie{
+		ImdbID: body.ImdbID,
+		Title:  body.Title,
+	}
+
+	// Series have a range of years such as "2008–2013", we keep the first one
+	if len(body.Year) >= 4 {
+		movie.Year, _ = strconv.Atoi(body.Year[:4])
+	}
+
+	if body.Poster != "N/A" {
+		movie.PosterURL = body.Poster
+	}
+
+	return movie, nil
+}
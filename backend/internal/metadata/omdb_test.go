@@ -0,0 +1,94 @@
+package metadata
+
+import (
+	"cinepulse.nlt.net/internal/data/movies"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestOMDb returns an OMDb provider calling a test server which answers with the given status and body
+func newTestOMDb(t *testing.T, status int, body string) *OMDb {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("apikey"); got != "test-key" {
+			t.Errorf("apikey = %q, want %q", got, "test-key")
+		}
+		if got := r.URL.Query().Get("i"); got != "tt0903747" {
+			t.Errorf("i = %q, want %q", got, "tt0903747")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	p := NewOMDb("test-key")
+	p.baseURL = srv.URL
+	return p
+}
+
+func TestOMDbFetchMovie(t *testing.T) {
+	p := newTestOMDb(t, http.StatusOK, `{
+		"Response": "True",
+		"imdbID": "tt0903747",
+		"Title": "Breaking Bad",
+		"Year": "2008–2013",
+		"Poster": "https://example.com/poster.jpg"
+	}`)
+
+	movie, err := p.FetchMovie(context.Background(), "tt0903747")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := movies.Movie{ImdbID: "tt0903747", Title: "Breaking Bad", Year: 2008, PosterURL: "https://example.com/poster.jpg"}
+	if movie.ImdbID != want.ImdbID || movie.Title != want.Title || movie.Year != want.Year || movie.PosterURL != want.PosterURL {
+		t.Errorf("got %+v, want %+v", *movie, want)
+	}
+}
+
+func TestOMDbFetchMovieWithoutPoster(t *testing.T) {
+	p := newTestOMDb(t, http.StatusOK, `{"Response": "True", "imdbID": "tt0903747", "Title": "Breaking Bad", "Year": "2008", "Poster": "N/A"}`)
+
+	movie, err := p.FetchMovie(context.Background(), "tt0903747")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if movie.PosterURL != "" {
+		t.Errorf("PosterURL = %q, want it empty", movie.PosterURL)
+	}
+}
+
+func TestOMDbFetchMovieErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		notFound bool
+	}{
+		{name: "unknown movie", status: http.StatusOK, body: `{"Response": "False", "Error": "Incorrect IMDb ID."}`, notFound: true},
+		{name: "invalid API key", status: http.StatusOK, body: `{"Response": "False", "Error": "Invalid API key!"}`},
+		{name: "server error", status: http.StatusInternalServerError, body: `{}`},
+		{name: "malformed body", status: http.StatusOK, body: `not json`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestOMDb(t, tt.status, tt.body)
+
+			_, err := p.FetchMovie(context.Background(), "tt0903747")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			// Only unknown movies may be reported as such, other failures mustn't reject the reviews
+			if got := errors.Is(err, movies.ErrMovieNotFound); got != tt.notFound {
+				t.Errorf("errors.Is(err, ErrMovieNotFound) = %v, want %v (err: %v)", got, tt.notFound, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+-- Create tokens table (activation, authentication, ...)
+CREATE TABLE IF NOT EXISTS tokens (
+                                      hash bytea PRIMARY KEY,
+                                      user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                      expiry TIMESTAMPTZ NOT NULL,
+                                      scope TEXT NOT NULL
+);
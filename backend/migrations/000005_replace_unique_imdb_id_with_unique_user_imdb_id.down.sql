@@ -0,0 +1,6 @@
+-- Note: this will fail if several users have already reviewed the same movie
+ALTER TABLE movie_reviews
+DROP CONSTRAINT IF EXISTS unique_user_imdb_id;
+
+ALTER TABLE movie_reviews
+ADD CONSTRAINT unique_imdb_id UNIQUE (imdb_id);
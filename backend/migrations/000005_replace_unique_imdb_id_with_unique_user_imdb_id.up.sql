@@ -0,0 +1,6 @@
+-- A movie can be reviewed by many users, but each user can only review a given movie once
+ALTER TABLE movie_reviews
+DROP CONSTRAINT IF EXISTS unique_imdb_id;
+
+ALTER TABLE movie_reviews
+ADD CONSTRAINT unique_user_imdb_id UNIQUE (user_id, imdb_id);
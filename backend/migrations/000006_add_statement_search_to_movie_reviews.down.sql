@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS movie_reviews_statement_tsv_idx;
+
+ALTER TABLE movie_reviews
+    DROP COLUMN IF EXISTS statement_tsv;
@@ -0,0 +1,6 @@
+-- Searchable version of the review statements, kept up to date by PostgreSQL itself
+ALTER TABLE movie_reviews
+    ADD COLUMN statement_tsv tsvector
+        GENERATED ALWAYS AS (to_tsvector('english', statement_comment)) STORED;
+
+CREATE INDEX IF NOT EXISTS movie_reviews_statement_tsv_idx ON movie_reviews USING GIN (statement_tsv);
@@ -0,0 +1,6 @@
+-- Create email_changes table (email addresses waiting to be confirmed, at most one per user)
+CREATE TABLE IF NOT EXISTS email_changes (
+                                             user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+                                             new_email citext NOT NULL,
+                                             created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,21 @@
+-- Create permissions table (what a user is allowed to do, e.g. writing or moderating reviews)
+CREATE TABLE IF NOT EXISTS permissions (
+                                           id BIGSERIAL PRIMARY KEY,
+                                           code TEXT NOT NULL UNIQUE
+);
+
+-- Create users_permissions table (permissions granted to each user)
+CREATE TABLE IF NOT EXISTS users_permissions (
+                                                 user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                                 permission_id BIGINT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+                                                 PRIMARY KEY (user_id, permission_id)
+);
+
+INSERT INTO permissions (code)
+VALUES ('reviews:write'), ('reviews:moderate'), ('users:admin');
+
+-- The users activated before permissions existed keep on being able to write reviews
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT u.id, p.id
+FROM users u, permissions p
+WHERE u.is_activated = TRUE AND p.code = 'reviews:write';
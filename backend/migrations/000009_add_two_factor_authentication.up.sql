@@ -0,0 +1,14 @@
+-- TOTP secret of the users, two-factor authentication is only required once the secret is verified
+ALTER TABLE users
+    ADD COLUMN totp_secret bytea,
+    ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN totp_last_step BIGINT;
+
+-- Create recovery_codes table (single-use codes replacing a TOTP code when the device is lost)
+CREATE TABLE IF NOT EXISTS recovery_codes (
+                                              hash bytea PRIMARY KEY,
+                                              user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                              created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
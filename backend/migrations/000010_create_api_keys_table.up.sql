@@ -0,0 +1,14 @@
+-- Create api_keys table (long-lived personal keys for scripts, restricted to some permissions)
+CREATE TABLE IF NOT EXISTS api_keys (
+                                        id BIGSERIAL PRIMARY KEY,
+                                        user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                        name VARCHAR(100) NOT NULL,
+                                        prefix TEXT NOT NULL,
+                                        hash bytea NOT NULL UNIQUE,
+                                        scopes TEXT[] NOT NULL,
+                                        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                        last_used_at TIMESTAMPTZ,
+                                        expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
@@ -0,0 +1,6 @@
+-- Date at which the account of a user asking for its deletion is purged, NULL when not scheduled
+ALTER TABLE users
+    ADD COLUMN deletion_scheduled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_deletion_scheduled_at_idx ON users (deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Create movies table (catalogue filled lazily from the metadata provider when a movie is first reviewed)
+CREATE TABLE IF NOT EXISTS movies (
+                                      imdb_id TEXT PRIMARY KEY,
+                                      title TEXT NOT NULL,
+                                      year INTEGER,
+                                      poster_url TEXT NOT NULL DEFAULT '',
+                                      fetched_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
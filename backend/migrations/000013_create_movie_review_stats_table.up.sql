@@ -0,0 +1,23 @@
+-- Create movie_review_stats table (rating aggregates of each movie, kept up to date as reviews change)
+CREATE TABLE IF NOT EXISTS movie_review_stats (
+                                                  imdb_id VARCHAR(20) PRIMARY KEY,
+                                                  review_count INTEGER NOT NULL DEFAULT 0,
+                                                  rating_sum INTEGER NOT NULL DEFAULT 0,
+                                                  rating_1_count INTEGER NOT NULL DEFAULT 0,
+                                                  rating_2_count INTEGER NOT NULL DEFAULT 0,
+                                                  rating_3_count INTEGER NOT NULL DEFAULT 0,
+                                                  rating_4_count INTEGER NOT NULL DEFAULT 0,
+                                                  rating_5_count INTEGER NOT NULL DEFAULT 0
+);
+
+-- Aggregate the existing reviews
+INSERT INTO movie_review_stats (imdb_id, review_count, rating_sum,
+                                rating_1_count, rating_2_count, rating_3_count, rating_4_count, rating_5_count)
+SELECT imdb_id, count(*), sum(rating),
+       count(*) FILTER (WHERE rating = 1),
+       count(*) FILTER (WHERE rating = 2),
+       count(*) FILTER (WHERE rating = 3),
+       count(*) FILTER (WHERE rating = 4),
+       count(*) FILTER (WHERE rating = 5)
+FROM movie_reviews
+GROUP BY imdb_id;
@@ -0,0 +1,2 @@
+-- The canonicalized IMDb IDs can't be restored, only the report is dropped
+DROP TABLE IF EXISTS imdb_id_migration_report;
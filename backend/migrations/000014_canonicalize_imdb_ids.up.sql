@@ -0,0 +1,67 @@
+-- Reviews whose imdb_id couldn't be canonicalized, to be fixed by hand
+CREATE TABLE IF NOT EXISTS imdb_id_migration_report (
+                                                        movie_review_id BIGINT PRIMARY KEY REFERENCES movie_reviews(id) ON DELETE CASCADE,
+                                                        imdb_id VARCHAR(20) NOT NULL,
+                                                        reason TEXT NOT NULL,
+                                                        reported_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Canonicalize the imdb_id of the existing reviews: trimmed, lowercase "tt" prefix and IMDb URLs
+-- reduced to their ID. The pattern is the same as the one of movies.CanonicalImdbID()
+DO $$
+DECLARE
+    review RECORD;
+    canonical TEXT;
+    fixed INTEGER := 0;
+    reported INTEGER := 0;
+BEGIN
+    FOR review IN
+        SELECT id, user_id, imdb_id
+        FROM movie_reviews
+        WHERE imdb_id !~ '^tt[0-9]{7,8}$'
+        ORDER BY id
+    LOOP
+        canonical := lower((regexp_match(
+            trim(review.imdb_id),
+            '^(?:(?:https?://)?(?:www\.|m\.)?imdb\.com/title/)?(tt[0-9]{7,8})(?:[/?#].*)?$',
+            'i'
+        ))[1]);
+
+        IF canonical IS NULL THEN
+            INSERT INTO imdb_id_migration_report (movie_review_id, imdb_id, reason)
+            VALUES (review.id, review.imdb_id, 'not an IMDb ID')
+            ON CONFLICT (movie_review_id) DO NOTHING;
+            reported := reported + 1;
+        ELSIF EXISTS (SELECT 1 FROM movie_reviews WHERE user_id = review.user_id AND imdb_id = canonical) THEN
+            -- Fixing it would break the unique_user_imdb_id constraint
+            INSERT INTO imdb_id_migration_report (movie_review_id, imdb_id, reason)
+            VALUES (review.id, review.imdb_id, 'the author already reviewed ' || canonical)
+            ON CONFLICT (movie_review_id) DO NOTHING;
+            reported := reported + 1;
+        ELSE
+            UPDATE movie_reviews SET imdb_id = canonical WHERE id = review.id;
+            fixed := fixed + 1;
+        END IF;
+    END LOOP;
+
+    RAISE NOTICE 'imdb_id canonicalization: % review(s) fixed, % review(s) reported in imdb_id_migration_report',
+        fixed, reported;
+END
+$$;
+
+-- The aggregates are keyed by imdb_id, so they are rebuilt from the fixed reviews
+TRUNCATE movie_review_stats;
+
+INSERT INTO movie_review_stats (imdb_id, review_count, rating_sum,
+                                rating_1_count, rating_2_count, rating_3_count, rating_4_count, rating_5_count)
+SELECT imdb_id, count(*), sum(rating),
+       count(*) FILTER (WHERE rating = 1),
+       count(*) FILTER (WHERE rating = 2),
+       count(*) FILTER (WHERE rating = 3),
+       count(*) FILTER (WHERE rating = 4),
+       count(*) FILTER (WHERE rating = 5)
+FROM movie_reviews
+GROUP BY imdb_id;
+
+-- Catalogued movies are fetched again under their canonical ID when next reviewed
+DELETE FROM movies WHERE imdb_id !~ '^tt[0-9]{7,8}$';
@@ -0,0 +1,11 @@
+-- Create movie_review_revisions table (the previous states of each review, one row per edit)
+CREATE TABLE IF NOT EXISTS movie_review_revisions (
+                                                      id BIGSERIAL PRIMARY KEY,
+                                                      movie_review_id BIGINT NOT NULL REFERENCES movie_reviews(id) ON DELETE CASCADE,
+                                                      version BIGINT NOT NULL,
+                                                      rating SMALLINT NOT NULL,
+                                                      statement_comment TEXT NOT NULL,
+                                                      statement_updated_at TIMESTAMPTZ NOT NULL,
+                                                      replaced_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                                      UNIQUE (movie_review_id, version)
+);
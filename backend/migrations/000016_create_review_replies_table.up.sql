@@ -0,0 +1,14 @@
+-- Create review_replies table. Replies either answer a review or, one level deep, another reply
+CREATE TABLE IF NOT EXISTS review_replies (
+                                              id BIGSERIAL PRIMARY KEY,
+                                              movie_review_id BIGINT NOT NULL REFERENCES movie_reviews(id) ON DELETE CASCADE,
+                                              parent_id BIGINT REFERENCES review_replies(id) ON DELETE CASCADE,
+                                              user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                              comment TEXT NOT NULL CHECK (char_length(comment) <= 280),
+                                              created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                              updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                              version BIGINT NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS review_replies_movie_review_id_idx ON review_replies (movie_review_id, created_at);
+CREATE INDEX IF NOT EXISTS review_replies_parent_id_idx ON review_replies (parent_id);
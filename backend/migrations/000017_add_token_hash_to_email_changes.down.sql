@@ -0,0 +1 @@
+ALTER TABLE email_changes DROP COLUMN IF EXISTS token_hash;
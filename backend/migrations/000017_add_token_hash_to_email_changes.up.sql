@@ -0,0 +1,7 @@
+-- Bind each pending email change to the token sent to the new address, so that only this token
+-- can confirm it. The pending changes can't be bound afterwards, they have to be requested again
+DELETE FROM email_changes;
+DELETE FROM tokens WHERE scope = 'email-change';
+
+ALTER TABLE email_changes
+    ADD COLUMN token_hash bytea NOT NULL REFERENCES tokens(hash) ON DELETE CASCADE;
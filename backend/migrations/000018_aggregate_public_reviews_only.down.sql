@@ -0,0 +1,13 @@
+-- Go back to aggregating all the reviews
+TRUNCATE movie_review_stats;
+
+INSERT INTO movie_review_stats (imdb_id, review_count, rating_sum,
+                                rating_1_count, rating_2_count, rating_3_count, rating_4_count, rating_5_count)
+SELECT imdb_id, count(*), sum(rating),
+       count(*) FILTER (WHERE rating = 1),
+       count(*) FILTER (WHERE rating = 2),
+       count(*) FILTER (WHERE rating = 3),
+       count(*) FILTER (WHERE rating = 4),
+       count(*) FILTER (WHERE rating = 5)
+FROM movie_reviews
+GROUP BY imdb_id;
@@ -0,0 +1,15 @@
+-- The movie aggregates only count the reviews of public users from now on, they are rebuilt accordingly
+TRUNCATE movie_review_stats;
+
+INSERT INTO movie_review_stats (imdb_id, review_count, rating_sum,
+                                rating_1_count, rating_2_count, rating_3_count, rating_4_count, rating_5_count)
+SELECT mr.imdb_id, count(*), sum(mr.rating),
+       count(*) FILTER (WHERE mr.rating = 1),
+       count(*) FILTER (WHERE mr.rating = 2),
+       count(*) FILTER (WHERE mr.rating = 3),
+       count(*) FILTER (WHERE mr.rating = 4),
+       count(*) FILTER (WHERE mr.rating = 5)
+FROM movie_reviews mr
+INNER JOIN users u ON u.id = mr.user_id
+WHERE u.is_protected = FALSE
+GROUP BY mr.imdb_id;